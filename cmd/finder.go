@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/model"
+	"github.com/spf13/viper"
+)
+
+// entryList holds the entries offered by the finder. The scanner keeps
+// adding to it while the finder is open, so access is guarded by its lock.
+//
+// The finder holds the lock while it reloads entries, and takes its own lock
+// while drawing the preview. Readers in the preview therefore use the separate
+// view lock, otherwise a reload and a redraw could block each other.
+//
+// Entries are inserted at their rank, which moves the ones behind them. The
+// indexes the finder hands out refer to the entries of its last reload, kept
+// in shown. The finder only reloads when the number of entries changes, a
+// project replacing the entry of its session keeps its label until then.
+type entryList struct {
+	sync.Mutex
+	view    sync.RWMutex
+	entries []model.Entry
+	// scores ranks added entries by frecency
+	scores map[string]float64
+	// shown holds the entries as the finder last read them
+	shown   []model.Entry
+	changed bool
+}
+
+// add inserts an entry at its rank, or in place of the entry of the running
+// session it opens, see core.AddEntry. It is safe to call while the
+// finder is running.
+func (l *entryList) add(entry model.Entry) {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = core.AddEntry(l.entries, entry, l.scores)
+	l.changed = true
+}
+
+// at returns the entry at finder index i without taking the finder lock.
+func (l *entryList) at(i int) model.Entry {
+	l.view.RLock()
+	defer l.view.RUnlock()
+	return l.shown[i]
+}
+
+// finderLock is the lock of the list as the finder takes it to reload. On
+// unlock the list remembers the entries the finder just read.
+type finderLock struct {
+	*entryList
+}
+
+func (l finderLock) Unlock() {
+	if l.changed || l.shown == nil {
+		l.view.Lock()
+		l.shown = slices.Clone(l.entries)
+		l.view.Unlock()
+		l.changed = false
+	}
+	l.Mutex.Unlock()
+}
+
+// previewWait is how long drawing a preview waits for git before showing
+// the pending preview instead
+const previewWait = 50 * time.Millisecond
+
+// previewer renders and caches the preview of entries, the git and README
+// lookups are too slow to repeat on every redraw. The finder draws previews
+// while it handles keys, so git runs in the background and the pending
+// preview is shown until it is done, from the next redraw on.
+type previewer struct {
+	sessions  []tmux.Session
+	configDir string
+	// slots bounds the number of git processes run at once
+	slots chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]*cachedPreview
+}
+
+// cachedPreview is the preview of an entry, text is the pending preview until
+// done is closed.
+type cachedPreview struct {
+	text string
+	done chan struct{}
+}
+
+func newPreviewer(sessions []tmux.Session) *previewer {
+	return &previewer{
+		sessions:  sessions,
+		configDir: filepath.Dir(viper.ConfigFileUsed()),
+		slots:     make(chan struct{}, 2),
+		cache:     map[string]*cachedPreview{},
+	}
+}
+
+func (p *previewer) preview(entry model.Entry) string {
+	// a project can take the place of the entry of its session, with the
+	// same label
+	key := entry.Path + "\x00" + entry.Session
+
+	p.mu.Lock()
+	cached, ok := p.cache[key]
+	if !ok {
+		cached = &cachedPreview{
+			text: core.PendingPreview(entry, p.sessions, p.configDir),
+			done: make(chan struct{}),
+		}
+		p.cache[key] = cached
+		go func() {
+			p.slots <- struct{}{}
+			text := core.Preview(entry, p.sessions, p.configDir)
+			<-p.slots
+
+			p.mu.Lock()
+			cached.text = text
+			p.mu.Unlock()
+			close(cached.done)
+		}()
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-cached.done:
+	case <-time.After(previewWait):
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return cached.text
+}
+
+// sessionMarkers prefix labels in the finder by the state of their session
+var sessionMarkers = map[core.SessionState]string{
+	core.NoSession:       "  ",
+	core.DetachedSession: "○ ",
+	core.AttachedSession: "● ",
+	core.CurrentSession:  "▶ ",
+}
+
+// liveSessions returns the running sessions and the name of the session we
+// are in, if any. Without a running server there are simply no sessions.
+func liveSessions() ([]tmux.Session, string) {
+	server := new(tmux.Server)
+	sessions, err := server.ListSessions(false)
+	if err != nil {
+		return nil, ""
+	}
+
+	current := ""
+	if server.Inside() {
+		if session, err := server.CurrentSession(); err == nil {
+			current = session.Name
+		}
+	}
+	return sessions, current
+}
+
+// search lets the user pick one of projects, labels are marked with the state
+// of their session in sessions.
+func search(projects *entryList, sessions []tmux.Session, current string, preview bool) (model.Entry, error) {
+	opts := []fuzzyfinder.Option{fuzzyfinder.WithHotReloadLock(finderLock{projects})}
+	if preview {
+		p := newPreviewer(sessions)
+		opts = append(opts, fuzzyfinder.WithPreviewWindow(func(i, width, height int) string {
+			if i < 0 {
+				return ""
+			}
+			return p.preview(projects.at(i))
+		}))
+	}
+
+	idx, err := fuzzyfinder.Find(
+		&projects.entries,
+		// the finder holds the lock while calling this
+		func(i int) string {
+			entry := projects.entries[i]
+			return sessionMarkers[core.SessionStateOf(entry, sessions, current)] + entry.Label
+		},
+		opts...,
+	)
+	if err != nil {
+		return model.Entry{}, err
+	}
+
+	return projects.at(idx), nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	rootCmd.AddCommand(freezeCmd)
+}
+
+// targetSession returns the named session, or the current one without a name.
+func targetSession(server *tmux.Server, args []string) tmux.Session {
+	if len(args) == 0 {
+		if !server.Inside() {
+			fmt.Fprintln(os.Stderr, "Not inside a session, pass a session name")
+			os.Exit(1)
+		}
+		session, err := server.CurrentSession()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return session
+	}
+
+	sessions, err := server.ListSessions(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	session, ok := findSession(sessions, args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "No session named %q\n", args[0])
+		os.Exit(1)
+	}
+	return session
+}
+
+var freezeCmd = &cobra.Command{
+	Use:   "freeze [session]",
+	Short: "Print a layout recreating a running session",
+	Long: `Print a layout with the windows, panes, directories and commands of a running
+session, the current one if no name is given.
+
+--write saves it as .sessionizer.yml in the session directory, --name as
+layouts/<name>.yml next to the config file. Existing files are only replaced
+with --force.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		write, _ := cmd.Flags().GetBool("write")
+		name, _ := cmd.Flags().GetString("name")
+		force, _ := cmd.Flags().GetBool("force")
+		if write && name != "" {
+			fmt.Fprintln(os.Stderr, "Pass either --write or --name")
+			os.Exit(1)
+		}
+
+		server := new(tmux.Server)
+		session := targetSession(server, args)
+		layout := core.FreezeSession(session)
+
+		var layoutPath string
+		switch {
+		case write:
+			layoutPath = filepath.Join(session.Path, core.LayoutFileName)
+		case name != "":
+			if viper.ConfigFileUsed() == "" {
+				fmt.Fprintln(os.Stderr, "Config file not found")
+				os.Exit(1)
+			}
+			layoutPath = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), "layouts", name+".yml")
+		default:
+			data, err := tmuxp.Marshal(layout)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print(string(data))
+			return
+		}
+
+		if err := tmuxp.WriteLayoutToFile(layout, layoutPath, force); err != nil {
+			if os.IsExist(err) {
+				fmt.Fprintf(os.Stderr, "%s exists (use --force)\n", layoutPath)
+				os.Exit(1)
+			}
+			log.Fatal(err)
+		}
+		fmt.Println(layoutPath)
+	},
+}
+
+func init() {
+	freezeCmd.Flags().BoolP("write", "w", false, "Write the layout to .sessionizer.yml in the session directory")
+	freezeCmd.Flags().StringP("name", "n", "", "Write the layout to layouts/<name>.yml next to the config file")
+	freezeCmd.Flags().BoolP("force", "f", false, "Replace an existing layout file")
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyPruneCmd)
+	historyCmd.AddCommand(historyClearCmd)
+}
+
+// loadHistory reads the history and returns it with its location.
+func loadHistory() (core.History, string) {
+	historyPath, err := core.HistoryPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+	history, err := core.LoadHistory(historyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return history, historyPath
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Print opened projects ranked by frecency",
+	Run: func(cmd *cobra.Command, args []string) {
+		AsJson, _ := cmd.Flags().GetBool("json")
+
+		history, _ := loadHistory()
+		now := time.Now()
+		visits := history.Ranked(now)
+
+		if AsJson {
+			json, _ := json.MarshalIndent(visits, "", "  ")
+			fmt.Println(string(json))
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SCORE\tCOUNT\tLAST OPENED\tLABEL\tPATH")
+		for _, v := range visits {
+			fmt.Fprintf(w, "%.2f\t%d\t%s\t%s\t%s\n", v.Frecency(now), v.Count, v.LastOpened.Format(time.DateTime), v.Label, v.Path)
+		}
+		w.Flush()
+	},
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove projects that no longer exist or were not opened recently",
+	Run: func(cmd *cobra.Command, args []string) {
+		olderThan, _ := cmd.Flags().GetDuration("older-than")
+
+		history, historyPath := loadHistory()
+		now := time.Now()
+		removed := history.Prune(func(v core.Visit) bool {
+			if olderThan > 0 && now.Sub(v.LastOpened) > olderThan {
+				return false
+			}
+			_, err := os.Stat(v.Path)
+			return err == nil
+		})
+		if err := history.Save(historyPath); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Removed %d projects from history\n", removed)
+	},
+}
+
+var historyClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Forget all opened projects",
+	Run: func(cmd *cobra.Command, args []string) {
+		// don't read the history, clearing must also work if it is corrupt
+		historyPath, err := core.HistoryPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := (core.History{}).Save(historyPath); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	historyCmd.Flags().BoolP("json", "", false, "Print json")
+	historyPruneCmd.Flags().Duration("older-than", 0, "Also remove projects last opened longer ago than this, e.g. 720h")
+}
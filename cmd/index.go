@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexRebuildCmd)
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the cached project index",
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Scan search directories and rewrite the project index",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := loadConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		indexPath, err := core.IndexPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		index, err := core.BuildIndex(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := index.Save(indexPath); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Indexed %d projects in %s\n", len(index.Entries), indexPath)
+	},
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	rootCmd.AddCommand(killCmd)
+}
+
+// protectedReason returns why a session must not be killed without --force,
+// or "" if it may be killed.
+func protectedReason(name string, current string) string {
+	if name == current {
+		return "it is the current session"
+	}
+	if defaultName := viper.GetString("default.name"); strings.TrimSpace(defaultName) != "" && name == tmux.NormalizeName(defaultName) {
+		return "it is the default session"
+	}
+	return ""
+}
+
+// pickSessions lets the user select any number of sessions in the finder.
+func pickSessions(sessions []tmux.Session, current string) ([]tmux.Session, error) {
+	idxs, err := fuzzyfinder.FindMulti(
+		sessions,
+		func(i int) string {
+			return sessionMarkers[core.StateOfSession(sessions[i], current)] + sessions[i].Name
+		},
+		fuzzyfinder.WithHeader("Tab to select, Enter to kill"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := make([]tmux.Session, 0, len(idxs))
+	for _, i := range idxs {
+		picked = append(picked, sessions[i])
+	}
+	return picked, nil
+}
+
+var killCmd = &cobra.Command{
+	Use:   "kill [name...]",
+	Short: "Kill sessions",
+	Long: `Kill the named sessions, all detached sessions, or sessions picked in the finder.
+
+The current session and the default session are never killed unless --force is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		detached, _ := cmd.Flags().GetBool("detached")
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		force, _ := cmd.Flags().GetBool("force")
+
+		modes := 0
+		for _, set := range []bool{len(args) > 0, detached, interactive} {
+			if set {
+				modes++
+			}
+		}
+		if modes != 1 {
+			fmt.Fprintln(os.Stderr, "Pass session names, --detached or --interactive")
+			os.Exit(1)
+		}
+
+		server := new(tmux.Server)
+		sessions, err := server.ListSessions(detached)
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, current := liveSessions()
+
+		var targets []tmux.Session
+		switch {
+		case detached:
+			targets = sessions
+		case interactive:
+			targets, err = pickSessions(sessions, current)
+			if err != nil {
+				return
+			}
+		default:
+			for _, name := range args {
+				session, ok := findSession(sessions, name)
+				if !ok {
+					fmt.Fprintf(os.Stderr, "No session named %q\n", name)
+					os.Exit(1)
+				}
+				targets = append(targets, session)
+			}
+		}
+
+		failed := false
+		for _, session := range targets {
+			if reason := protectedReason(session.Name, current); reason != "" && !force {
+				fmt.Fprintf(os.Stderr, "Not killing %s: %s (use --force)\n", session.Name, reason)
+				failed = true
+				continue
+			}
+			if err := server.KillSession(session.Name); err != nil {
+				fmt.Fprintf(os.Stderr, "Error killing session %s: %s\n", session.Name, err)
+				failed = true
+				continue
+			}
+			fmt.Println(session.Name)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// findSession looks up a session by its exact name, or by the name sessionizer
+// would give a session for it.
+func findSession(sessions []tmux.Session, name string) (tmux.Session, bool) {
+	for _, candidate := range []string{name, tmux.NormalizeName(name)} {
+		for _, s := range sessions {
+			if s.Name == candidate {
+				return s, true
+			}
+		}
+	}
+	return tmux.Session{}, false
+}
+
+func init() {
+	killCmd.Flags().BoolP("detached", "d", false, "Kill all detached sessions")
+	killCmd.Flags().BoolP("interactive", "i", false, "Pick sessions to kill in the finder")
+	killCmd.Flags().BoolP("force", "f", false, "Also kill the current and the default session")
+}
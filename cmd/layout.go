@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	rootCmd.AddCommand(layoutCmd)
+	layoutCmd.AddCommand(layoutApplyCmd)
+	layoutCmd.AddCommand(layoutValidateCmd)
+}
+
+// confirm asks a yes/no question on the terminal, no being the default.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+var layoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "Manage the layouts of sessions",
+}
+
+var layoutApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply the layout of a running session again",
+	Long: `Apply the layout of a running session again, the current one unless --session
+is given, to pick up changes made to the layout since the session was started.
+
+Windows and panes the session lacks are added, windows are matched by name and
+unnamed ones by position. Existing panes are left alone. --reset instead
+rebuilds the whole session from the layout, after asking, which ends everything
+running in it.`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("session")
+		reset, _ := cmd.Flags().GetBool("reset")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		server := new(tmux.Server)
+		var target []string
+		if name != "" {
+			target = []string{name}
+		}
+		session := targetSession(server, target)
+
+		config, err := loadConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		configDir := filepath.Dir(viper.ConfigFileUsed())
+		layout, layoutPath, err := core.ReadSessionLayout(session, allEntries(config, false), configDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if layout == nil {
+			fmt.Fprintf(os.Stderr, "No layout for session %s\n", session.Name)
+			os.Exit(1)
+		}
+
+		if reset {
+			if !yes && !confirm(fmt.Sprintf("Rebuild session %s from %s, ending everything running in it?", session.Name, layoutPath)) {
+				os.Exit(1)
+			}
+			fmt.Printf("Rebuilding %s\n", session.Name)
+			if _, err := core.RebuildSession(server, session, *layout); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		changes, err := core.ReconcileLayout(server, session, *layout)
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(changes) == 0 {
+			fmt.Printf("Session %s matches %s\n", session.Name, layoutPath)
+		}
+	},
+}
+
+var layoutValidateCmd = &cobra.Command{
+	Use:     "validate [file...]",
+	Aliases: []string{"lint"},
+	Short:   "Check layout files for problems",
+	Long: `Check layout files for unknown keys, unknown window layouts, missing start
+directories, windows with more than one focused pane and everything else that
+keeps a layout from being applied. Problems are printed with their file, line
+and column.
+
+--all checks every layout in layouts/ next to the config file and the layout of
+every project.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// files given by path are checked without a config
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			initConfig()
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) > 0) {
+			fmt.Fprintln(os.Stderr, "Pass either layout files or --all")
+			os.Exit(1)
+		}
+
+		files := args
+		if all {
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatal(err)
+			}
+			configDir := filepath.Dir(viper.ConfigFileUsed())
+			files = core.LayoutFiles(allEntries(config, false), configDir)
+		}
+
+		failed := 0
+		for _, file := range files {
+			problems, err := tmuxp.ValidateFile(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed++
+				continue
+			}
+			for _, problem := range problems {
+				if problem.Line == 0 {
+					fmt.Printf("%s: %s\n", file, problem)
+				} else {
+					fmt.Printf("%s:%s\n", file, problem)
+				}
+			}
+			if len(problems) > 0 {
+				failed++
+			}
+		}
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d of %d layouts have problems\n", failed, len(files))
+			os.Exit(1)
+		}
+		fmt.Printf("Checked %d layouts, no problems\n", len(files))
+	},
+}
+
+func init() {
+	layoutValidateCmd.Flags().Bool("all", false, "Check all layouts of the config and its projects")
+	layoutApplyCmd.Flags().String("session", "", "Name of the session, the current one if not given")
+	layoutApplyCmd.Flags().Bool("reset", false, "Rebuild the session from the layout instead of adding what is missing")
+	layoutApplyCmd.Flags().BoolP("yes", "y", false, "Rebuild without asking")
+}
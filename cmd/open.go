@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// exitAmbiguous is returned when a query matches more than one entry
+	exitAmbiguous = 2
+	// exitNoMatch is returned when a query matches no entry
+	exitNoMatch = 3
+)
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+}
+
+var openCmd = &cobra.Command{
+	Use:   "open <query>",
+	Short: "Start or switch to the session of the entry matching query",
+	Long: `Start or switch to the session of the entry matching query, without the finder.
+
+The query matches an entry by its exact label, then by label ignoring case, then
+by fuzzy matching like the finder does, which must find a single entry.
+Exits with 2 and lists the candidates if the query is ambiguous, and with 3 if
+nothing matches.`,
+	Args: cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		query := args[0]
+
+		config, err := loadConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		rebuild, _ := cmd.Flags().GetBool("rebuild-index")
+		matches := core.Match(allEntries(config, rebuild), query)
+
+		switch len(matches) {
+		case 0:
+			fmt.Fprintf(os.Stderr, "No entry matches %q\n", query)
+			os.Exit(exitNoMatch)
+		case 1:
+		default:
+			fmt.Fprintf(os.Stderr, "%q matches %d entries:\n", query, len(matches))
+			for _, m := range matches {
+				fmt.Fprintln(os.Stderr, m.Label)
+			}
+			os.Exit(exitAmbiguous)
+		}
+
+		project := matches[0]
+		printPath, _ := cmd.Flags().GetBool("print-path")
+		if printPath {
+			fmt.Println(project.Path)
+			return
+		}
+		startSession(cmd, project)
+	},
+}
+
+func init() {
+	openCmd.Flags().Bool("rebuild-index", false, "Scan search directories instead of using the cached project index")
+	openCmd.Flags().Bool("print-path", false, "Print matched path to stdout instead of starting a tmux session")
+	openCmd.Flags().Bool("keep-partial", false, "Keep a new session whose layout failed, instead of killing it")
+	openCmd.Flags().Bool("dry-run", false, "Print the tmux commands starting the matched session instead of running them")
+	openCmd.Flags().BoolP("json", "", false, "Print the dry run as json")
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Recreate sessions from a snapshot",
+	Long: `Recreate the sessions, windows and panes of a snapshot taken with save.
+
+Sessions that already exist are skipped. Commands that ran in panes are started
+again, unless --no-commands is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		noCommands, _ := cmd.Flags().GetBool("no-commands")
+
+		snapshot, err := core.LoadSnapshot(snapshotPath(cmd))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		restored, skipped, err := core.RestoreSnapshot(new(tmux.Server), snapshot, !noCommands)
+		for _, name := range skipped {
+			fmt.Fprintf(os.Stderr, "Skipping %s: session exists\n", name)
+		}
+		for _, name := range restored {
+			fmt.Println(name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	restoreCmd.Flags().String("file", "", "Read the snapshot from this file instead of the state directory")
+	restoreCmd.Flags().Bool("no-commands", false, "Don't start the commands that ran in panes")
+}
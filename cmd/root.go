@@ -59,6 +59,7 @@ func configureViper() {
 	viper.AddConfigPath("$HOME/.config/sessionizer")
 
 	viper.SetDefault("base.ignore", "")
+	viper.SetDefault("base.ignore_files", true)
 }
 
 func initConfig() {
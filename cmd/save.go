@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(saveCmd)
+}
+
+// snapshotPath returns the file given with --file, or the default snapshot.
+func snapshotPath(cmd *cobra.Command) string {
+	if file, _ := cmd.Flags().GetString("file"); file != "" {
+		return file
+	}
+	path, err := core.SnapshotPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return path
+}
+
+var saveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Save all sessions to a snapshot",
+	Run: func(cmd *cobra.Command, args []string) {
+		path := snapshotPath(cmd)
+
+		snapshot, err := core.TakeSnapshot(new(tmux.Server))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := snapshot.Save(path); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Saved %d sessions to %s\n", len(snapshot.Sessions), path)
+	},
+}
+
+func init() {
+	saveCmd.Flags().String("file", "", "Write the snapshot to this file instead of the state directory")
+}
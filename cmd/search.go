@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
-	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
 	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/util"
 	"github.com/oschrenk/sessionizer/model"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,38 +20,59 @@ func init() {
 	rootCmd.AddCommand(searchCmd)
 }
 
-func search(projects []model.Entry) (model.Entry, error) {
-	idx, err := fuzzyfinder.Find(
-		projects,
-		func(i int) string {
-			return projects[i].Label
-		},
-	)
-	if err != nil {
-		return model.Entry{}, err
+// startOptions reads the flags changing how sessions are created.
+func startOptions(cmd *cobra.Command) core.StartOptions {
+	keepPartial, _ := cmd.Flags().GetBool("keep-partial")
+	options := core.StartOptions{KeepPartial: keepPartial}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		options.Plan = new(tmux.Plan)
 	}
-	return projects[idx], nil
+	return options
 }
 
-func startSession(project model.Entry) {
-	configDir := filepath.Dir(viper.ConfigFileUsed())
-	err := core.StartSession(project.Label, project.Path, project.Layout, project.LayoutPath, configDir)
-	if err != nil {
-		panic(err)
+// printPlan prints the tmux commands of a dry run, as text or json.
+func printPlan(cmd *cobra.Command, plan *tmux.Plan) {
+	AsJson, _ := cmd.Flags().GetBool("json")
+	if AsJson {
+		json, _ := json.MarshalIndent(plan.Commands, "", "  ")
+		fmt.Println(string(json))
+	} else {
+		fmt.Print(plan)
 	}
 }
 
-func mapF[T, V interface{}](ts []T, fn func(T) V) []V {
-	result := make([]V, len(ts))
-	for i, t := range ts {
-		result[i] = fn(t)
+func startSession(cmd *cobra.Command, project model.Entry) {
+	options := startOptions(cmd)
+
+	// entries of running sessions only need to be switched to
+	if project.Session != "" {
+		if options.Plan != nil {
+			if err := tmux.DryRun(options.Plan).AttachSession(tmux.Session{Name: project.Session}); err != nil {
+				log.Fatal(err)
+			}
+			printPlan(cmd, options.Plan)
+			return
+		}
+		if err := core.AttachSession(project.Session); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	options.Vars = project.Vars
+	configDir := filepath.Dir(viper.ConfigFileUsed())
+	err := core.StartSession(project.Label, project.Path, project.Layout, project.LayoutPath, configDir, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if options.Plan != nil {
+		printPlan(cmd, options.Plan)
 	}
-	return result
 }
 
 // parseSearchEntries parses search.entries which can be a mix of strings and objects.
 // Strings are treated as paths (name auto-derived). Objects must have a "path" key
-// and an optional "name" key.
+// and may set "name", "layout" and "vars", the variables of the layout template.
 func parseSearchEntries(raw interface{}) ([]model.SearchEntry, error) {
 	if raw == nil {
 		return nil, nil
@@ -74,6 +98,12 @@ func parseSearchEntries(raw interface{}) ([]model.SearchEntry, error) {
 			if layout, ok := v["layout"].(string); ok {
 				entry.Layout = layout
 			}
+			if vars, ok := v["vars"].(map[string]interface{}); ok {
+				entry.Vars = make(map[string]string, len(vars))
+				for key, value := range vars {
+					entry.Vars[key] = fmt.Sprint(value)
+				}
+			}
 			entries = append(entries, entry)
 		default:
 			return nil, fmt.Errorf("search.entries[%d]: expected string or object, got %T", i, item)
@@ -82,6 +112,176 @@ func parseSearchEntries(raw interface{}) ([]model.SearchEntry, error) {
 	return entries, nil
 }
 
+// loadConfig builds the application configuration from viper.
+func loadConfig() (model.Config, error) {
+	searchEntries, err := parseSearchEntries(viper.Get("search.entries"))
+	if err != nil {
+		return model.Config{}, err
+	}
+	searchDirs, err := parseSearchDirs(viper.Get("search.directories"))
+	if err != nil {
+		return model.Config{}, err
+	}
+
+	return model.Config{
+		DefaultName:          viper.GetString("default.name"),
+		DefaultPath:          os.ExpandEnv(viper.GetString("default.path")),
+		DefaultLayoutPath:    viper.GetString("default.layout_path"),
+		SearchDirs:           searchDirs,
+		SearchEntries:        searchEntries,
+		Ignore:               viper.GetStringSlice("base.ignore"),
+		IgnoreFiles:          viper.GetBool("base.ignore_files"),
+		RooterPatterns:       viper.GetStringSlice("base.rooter_patterns"),
+		NestedRooterPatterns: viper.GetStringSlice("search.nested_rooter_patterns"),
+		Worktrees:            viper.GetBool("search.worktrees"),
+		Workers:              viper.GetInt("search.workers"),
+	}, nil
+}
+
+// loadEntries returns the entries that are available right away and starts
+// scanning the search directories in the background, see core.IndexedEntries.
+// Discovered projects are passed to emit. If the index cannot be located, the
+// scan still runs but its result is not cached.
+func loadEntries(config model.Config, rebuild bool, emit func(model.Entry)) ([]model.Entry, <-chan error) {
+	indexPath, err := core.IndexPath()
+	if err != nil {
+		util.DebugLog("index: %v", err)
+		indexPath = ""
+	}
+	return core.IndexedEntries(config, indexPath, rebuild, emit)
+}
+
+// allEntries returns every searchable entry, waiting for the background scan
+// to finish when the index had to be rebuilt.
+func allEntries(config model.Config, rebuild bool) []model.Entry {
+	projects := &entryList{}
+	projects.Lock()
+	var refreshed <-chan error
+	projects.entries, refreshed = loadEntries(config, rebuild, projects.add)
+	projects.Unlock()
+	waitForIndex(refreshed)
+
+	projects.Lock()
+	defer projects.Unlock()
+	return projects.entries
+}
+
+// frecencyScores returns the frecency of every path opened before. Without a
+// readable history there are no scores and the order of entries is left
+// untouched.
+func frecencyScores() map[string]float64 {
+	historyPath, err := core.HistoryPath()
+	if err != nil {
+		util.DebugLog("history: %v", err)
+		return nil
+	}
+	history, err := core.LoadHistory(historyPath)
+	if err != nil {
+		util.DebugLog("history: %v", err)
+		return nil
+	}
+	return history.Scores(time.Now())
+}
+
+// waitForIndex blocks until the background scan finished. Failing to
+// update the cache is not fatal, the next run scans again.
+func waitForIndex(done <-chan error) {
+	if err := <-done; err != nil {
+		util.DebugLog("index: %v", err)
+	}
+}
+
+// parseSearchDirs parses search.directories which can be a mix of strings and objects.
+// Strings are treated as paths scanned with the global settings. Objects must have
+// a "path" key and may set "min_depth", "max_depth", "follow_symlinks", "ignore",
+// "rooter_patterns" and "nested_rooter_patterns" for that directory.
+func parseSearchDirs(raw interface{}) ([]model.SearchDir, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	slice, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("search.directories: expected array, got %T", raw)
+	}
+	dirs := make([]model.SearchDir, 0, len(slice))
+	for i, item := range slice {
+		switch v := item.(type) {
+		case string:
+			dirs = append(dirs, model.SearchDir{Path: os.ExpandEnv(v)})
+		case map[string]interface{}:
+			path, ok := v["path"].(string)
+			if !ok {
+				return nil, fmt.Errorf("search.directories[%d]: missing or invalid 'path'", i)
+			}
+			dir := model.SearchDir{Path: os.ExpandEnv(path)}
+			var err error
+			if dir.MinDepth, err = intValue(v["min_depth"]); err != nil {
+				return nil, fmt.Errorf("search.directories[%d].min_depth: %w", i, err)
+			}
+			if dir.MaxDepth, err = intValue(v["max_depth"]); err != nil {
+				return nil, fmt.Errorf("search.directories[%d].max_depth: %w", i, err)
+			}
+			if follow, ok := v["follow_symlinks"].(bool); ok {
+				dir.FollowSymlinks = follow
+			}
+			if dir.Ignore, err = stringsValue(v["ignore"]); err != nil {
+				return nil, fmt.Errorf("search.directories[%d].ignore: %w", i, err)
+			}
+			if dir.RooterPatterns, err = stringsValue(v["rooter_patterns"]); err != nil {
+				return nil, fmt.Errorf("search.directories[%d].rooter_patterns: %w", i, err)
+			}
+			if dir.NestedRooterPatterns, err = stringsValue(v["nested_rooter_patterns"]); err != nil {
+				return nil, fmt.Errorf("search.directories[%d].nested_rooter_patterns: %w", i, err)
+			}
+			dirs = append(dirs, dir)
+		default:
+			return nil, fmt.Errorf("search.directories[%d]: expected string or object, got %T", i, item)
+		}
+	}
+	return dirs, nil
+}
+
+// intValue converts an optional, non-negative config number.
+func intValue(raw interface{}) (int, error) {
+	switch v := raw.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		if v >= 0 {
+			return int(v), nil
+		}
+	case int:
+		if v >= 0 {
+			return v, nil
+		}
+	case float64:
+		if v >= 0 && v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("expected non-negative integer, got %v", raw)
+}
+
+// stringsValue converts an optional config array of strings.
+func stringsValue(raw interface{}) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	slice, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected array, got %T", raw)
+	}
+	result := make([]string, 0, len(slice))
+	for _, item := range slice {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", item)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
 var searchCmd = &cobra.Command{
 	Use:   "search",
 	Short: "Search sessions",
@@ -89,34 +289,36 @@ var searchCmd = &cobra.Command{
 		initConfig()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		searchEntries, err := parseSearchEntries(viper.Get("search.entries"))
+		config, err := loadConfig()
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		config := model.Config{
-			DefaultName:       viper.GetString("default.name"),
-			DefaultPath:       os.ExpandEnv(viper.GetString("default.path")),
-			DefaultLayoutPath: viper.GetString("default.layout_path"),
-			SearchDirs:        mapF(viper.GetStringSlice("search.directories"), os.ExpandEnv),
-			SearchEntries:     searchEntries,
-			Ignore:            viper.GetStringSlice("base.ignore"),
-			RooterPatterns:    viper.GetStringSlice("base.rooter_patterns"),
-		}
-
-		// build entries
-		projects, err := core.BuildEntries(config)
-		if err != nil {
-			log.Fatal(err)
-		}
+		// offer the default session, search entries and cached projects
+		// immediately, projects discovered by the scan stream in behind them
+		rebuild, _ := cmd.Flags().GetBool("rebuild-index")
+		projects := &entryList{scores: frecencyScores()}
+		projects.Lock()
+		var refreshed <-chan error
+		projects.entries, refreshed = loadEntries(config, rebuild, projects.add)
+		// running sessions that belong to no project can be picked as well
+		sessions, current := liveSessions()
+		projects.entries = append(projects.entries, core.SessionEntries(projects.entries, sessions)...)
+		core.RankEntries(projects.entries, projects.scores)
+		projects.Unlock()
+		// the background scan can outlive the finder, let it finish
+		// so the next search starts from an up-to-date index
+		defer waitForIndex(refreshed)
 
 		// search, select entry
-		project, err := search(projects)
+		noPreview, _ := cmd.Flags().GetBool("no-preview")
+		project, err := search(projects, sessions, current, !noPreview)
 		if err != nil {
 			printPath, _ := cmd.Flags().GetBool("print-path")
 			if printPath {
 				return
 			}
+			waitForIndex(refreshed)
 			log.Fatal(err)
 		}
 
@@ -125,10 +327,15 @@ var searchCmd = &cobra.Command{
 			fmt.Println(project.Path)
 			return
 		}
-		startSession(project)
+		startSession(cmd, project)
 	},
 }
 
 func init() {
+	searchCmd.Flags().Bool("rebuild-index", false, "Scan search directories instead of using the cached project index")
+	searchCmd.Flags().Bool("no-preview", false, "Don't show the preview window")
 	searchCmd.Flags().Bool("print-path", false, "Print selected path to stdout instead of starting a tmux session")
+	searchCmd.Flags().Bool("keep-partial", false, "Keep a new session whose layout failed, instead of killing it")
+	searchCmd.Flags().Bool("dry-run", false, "Print the tmux commands starting the selected session instead of running them")
+	searchCmd.Flags().BoolP("json", "", false, "Print the dry run as json")
 }
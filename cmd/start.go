@@ -36,14 +36,21 @@ var startCmd = &cobra.Command{
 		defaultLayoutPath := viper.GetString("default.layout_path")
 
 		configDir := filepath.Dir(viper.ConfigFileUsed())
-		err := core.StartSession(name, defaultPath, "", defaultLayoutPath, configDir)
+		options := startOptions(cmd)
+		err := core.StartSession(name, defaultPath, "", defaultLayoutPath, configDir, options)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error switching to session: %s", name)
+			fmt.Fprintf(os.Stderr, "Error switching to session %s: %v\n", name, err)
 			os.Exit(1)
 		}
+		if options.Plan != nil {
+			printPlan(cmd, options.Plan)
+		}
 	},
 }
 
 func init() {
 	startCmd.Flags().StringP("name", "n", "", "Session name to start (overrides default.name)")
+	startCmd.Flags().Bool("keep-partial", false, "Keep a new session whose layout failed, instead of killing it")
+	startCmd.Flags().Bool("dry-run", false, "Print the tmux commands starting the session instead of running them")
+	startCmd.Flags().BoolP("json", "", false, "Print the dry run as json")
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/oschrenk/sessionizer/core"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(switchCmd)
+}
+
+// loadMRU reads the session stack and returns it with its location. Without a
+// readable stack, the order tmux reports is used.
+func loadMRU() (core.MRU, string) {
+	mruPath, err := core.MRUPath()
+	if err != nil {
+		util.DebugLog("mru: %v", err)
+		return core.MRU{}, ""
+	}
+	mru, err := core.LoadMRU(mruPath)
+	if err != nil {
+		util.DebugLog("mru: %v", err)
+		return core.MRU{}, mruPath
+	}
+	return mru, mruPath
+}
+
+var switchCmd = &cobra.Command{
+	Use:   "switch [name]",
+	Short: "Switch sessions in most recently used order",
+	Long: `Switch to the named session, or to another session in most recently used order.
+
+--last switches to the session used before the current one. --next and --prev
+cycle through all sessions, keeping the order they started with until another
+session is switched to by other means.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		last, _ := cmd.Flags().GetBool("last")
+		next, _ := cmd.Flags().GetBool("next")
+		prev, _ := cmd.Flags().GetBool("prev")
+
+		modes := 0
+		for _, set := range []bool{len(args) > 0, last, next, prev} {
+			if set {
+				modes++
+			}
+		}
+		if modes != 1 {
+			fmt.Fprintln(os.Stderr, "Pass a session name, --last, --next or --prev")
+			os.Exit(1)
+		}
+
+		server := new(tmux.Server)
+		sessions, err := server.ListSessions(false)
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, current := liveSessions()
+
+		mru, mruPath := loadMRU()
+		mru.Update(sessions)
+
+		var target string
+		var ok bool
+		switch {
+		case last:
+			target, ok = mru.Last(current)
+		case next:
+			target, ok = mru.Step(current, 1)
+		case prev:
+			target, ok = mru.Step(current, -1)
+		default:
+			var session tmux.Session
+			session, ok = findSession(sessions, args[0])
+			if !ok {
+				fmt.Fprintf(os.Stderr, "No session named %q\n", args[0])
+				os.Exit(1)
+			}
+			target = session.Name
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "No other session to switch to")
+			os.Exit(1)
+		}
+
+		// save before switching, attaching from outside of tmux only
+		// returns once the client detaches
+		mru.Touch(target, time.Now())
+		if mruPath != "" {
+			if err := mru.Save(mruPath); err != nil {
+				util.DebugLog("mru: %v", err)
+			}
+		}
+
+		if err := core.AttachSession(target); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	switchCmd.Flags().BoolP("last", "l", false, "Switch to the previously used session")
+	switchCmd.Flags().BoolP("next", "n", false, "Switch to the next session in most recently used order")
+	switchCmd.Flags().BoolP("prev", "p", false, "Switch to the previous session in most recently used order")
+}
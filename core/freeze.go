@@ -0,0 +1,51 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+)
+
+// FreezeSession converts a running session into a layout that recreates its
+// windows, panes, directories and commands when the session is started again.
+func FreezeSession(session tmux.Session) tmuxp.Layout {
+	return freezeSession(session, foregroundCommand)
+}
+
+// freezeSession converts a running session, using command to look up the
+// command line running in a pane. Directories equal to the one they would
+// default to are left out, so the layout stays short.
+func freezeSession(session tmux.Session, command func(tmux.Pane) string) tmuxp.Layout {
+	windows := slices.Clone(session.Windows)
+	slices.SortFunc(windows, func(a, b tmux.Window) int { return a.Index - b.Index })
+
+	layout := tmuxp.Layout{}
+	for _, window := range windows {
+		panes := slices.Clone(window.Panes)
+		slices.SortFunc(panes, func(a, b tmux.Pane) int { return a.Index - b.Index })
+		if len(panes) == 0 {
+			continue
+		}
+
+		w := tmuxp.Window{Name: window.Name, Focus: window.Active, Layout: tmuxp.LayoutType(window.Layout)}
+		windowDir := session.Path
+		if panes[0].Path != session.Path {
+			w.StartDirectory = panes[0].Path
+			windowDir = panes[0].Path
+		}
+
+		for _, pane := range panes {
+			p := tmuxp.Pane{Focus: pane.Active}
+			if pane.Path != windowDir {
+				p.StartDirectory = pane.Path
+			}
+			if !isShell(pane.Command) {
+				p.ShellCommand = []string{command(pane)}
+			}
+			w.Panes = append(w.Panes, p)
+		}
+		layout.Windows = append(layout.Windows, w)
+	}
+	return layout
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+)
+
+func TestFreezeSession(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+
+	session := tmux.Session{
+		Name: "org/app",
+		Path: "/p/app",
+		Windows: []tmux.Window{
+			{Index: 2, Name: "logs", Layout: "b260,80x24,0,0,3", Panes: []tmux.Pane{
+				{Index: 0, Command: "tail", Path: "/var/log", Active: true},
+			}},
+			{Index: 1, Name: "edit", Active: true, Layout: "020a,80x24,0,0{40x24,0,0,1,39x24,41,0,2}", Panes: []tmux.Pane{
+				{Index: 1, Command: "zsh", Path: "/p/app/src", Active: true},
+				{Index: 0, Command: "nvim", Path: "/p/app"},
+			}},
+		},
+	}
+	command := func(pane tmux.Pane) string {
+		if pane.Command == "tail" {
+			return "tail -f system.log"
+		}
+		return pane.Command
+	}
+
+	want := tmuxp.Layout{Windows: []tmuxp.Window{
+		{Name: "edit", Focus: true, Layout: "020a,80x24,0,0{40x24,0,0,1,39x24,41,0,2}", Panes: []tmuxp.Pane{
+			{ShellCommand: []string{"nvim"}},
+			{StartDirectory: "/p/app/src", Focus: true},
+		}},
+		{Name: "logs", Layout: "b260,80x24,0,0,3", StartDirectory: "/var/log", Panes: []tmuxp.Pane{
+			{ShellCommand: []string{"tail -f system.log"}, Focus: true},
+		}},
+	}}
+
+	got := freezeSession(session, command)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("freezeSession() = %+v, want %+v", got, want)
+	}
+
+	// the frozen layout can be read back as a layout file
+	layoutPath := filepath.Join(t.TempDir(), LayoutFileName)
+	if err := tmuxp.WriteLayoutToFile(got, layoutPath, false); err != nil {
+		t.Fatalf("WriteLayoutToFile() error: %v", err)
+	}
+	if err := tmuxp.WriteLayoutToFile(got, layoutPath, false); err == nil {
+		t.Errorf("WriteLayoutToFile() overwrote an existing file")
+	}
+	read, err := tmuxp.ReadLayoutFromFile(layoutPath)
+	if err != nil {
+		t.Fatalf("ReadLayoutFromFile() error: %v", err)
+	}
+	if !reflect.DeepEqual(*read, want) {
+		t.Errorf("ReadLayoutFromFile() = %+v, want %+v", *read, want)
+	}
+}
+
+func TestFreezeSessionTemplateSyntax(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+
+	session := tmux.Session{
+		Name: "app",
+		Path: "/p/app",
+		Windows: []tmux.Window{
+			{Index: 0, Name: "docker", Active: true, Panes: []tmux.Pane{
+				{Index: 0, Command: "docker", Path: "/p/app", Active: true},
+			}},
+		},
+	}
+	format := "docker ps --format '{{.Names}}'"
+	command := func(tmux.Pane) string { return format }
+
+	// layouts are read as templates, frozen commands must come back as
+	// they were running
+	layoutPath := filepath.Join(t.TempDir(), LayoutFileName)
+	if err := tmuxp.WriteLayoutToFile(freezeSession(session, command), layoutPath, false); err != nil {
+		t.Fatalf("WriteLayoutToFile() error: %v", err)
+	}
+	read, err := tmuxp.ReadLayoutTemplate(layoutPath, templateData("app", "/p/app", nil))
+	if err != nil {
+		t.Fatalf("ReadLayoutTemplate() error: %v", err)
+	}
+	if got := read.Windows[0].Panes[0].ShellCommand; len(got) != 1 || got[0] != format {
+		t.Errorf("ReadLayoutTemplate() command = %q, want %q", got, format)
+	}
+}
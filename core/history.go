@@ -0,0 +1,178 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/oschrenk/sessionizer/internal/xdg"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+const historyVersion = 1
+
+const historyFileName = "history.json"
+
+// Visit records how often and how recently a project was opened.
+type Visit struct {
+	Path       string    `json:"path"`
+	Label      string    `json:"label"`
+	Count      int       `json:"count"`
+	LastOpened time.Time `json:"last_opened"`
+}
+
+// History is the local store of opened projects, used to rank entries.
+type History struct {
+	Version int     `json:"version"`
+	Visits  []Visit `json:"visits"`
+}
+
+// HistoryPath returns the location of the history in the XDG data directory.
+func HistoryPath() (string, error) {
+	dir, err := xdg.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyFileName), nil
+}
+
+// LoadHistory reads the history from path. A missing file is an empty history.
+func LoadHistory(path string) (History, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return History{Version: historyVersion}, nil
+	}
+	if err != nil {
+		return History{}, err
+	}
+
+	var history History
+	if err := json.Unmarshal(data, &history); err != nil {
+		return History{}, err
+	}
+	return history, nil
+}
+
+// Save writes the history to path.
+func (h History) Save(path string) error {
+	h.Version = historyVersion
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Record registers that the project at path was opened at the given time.
+func (h *History) Record(label string, path string, at time.Time) {
+	for i := range h.Visits {
+		if h.Visits[i].Path == path {
+			h.Visits[i].Label = label
+			h.Visits[i].Count++
+			h.Visits[i].LastOpened = at
+			return
+		}
+	}
+	h.Visits = append(h.Visits, Visit{Path: path, Label: label, Count: 1, LastOpened: at})
+}
+
+// Frecency scores a visit by frequency × recency: the open count is weighted
+// by how long ago the project was last opened.
+func (v Visit) Frecency(now time.Time) float64 {
+	age := now.Sub(v.LastOpened)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(v.Count) * weight
+}
+
+// Frecency returns the score of the project at path, 0 if it was never opened.
+func (h History) Frecency(path string, now time.Time) float64 {
+	for _, v := range h.Visits {
+		if v.Path == path {
+			return v.Frecency(now)
+		}
+	}
+	return 0
+}
+
+// Ranked returns the visits ordered by frecency, highest first.
+func (h History) Ranked(now time.Time) []Visit {
+	visits := slices.Clone(h.Visits)
+	slices.SortStableFunc(visits, func(a, b Visit) int {
+		return compareScores(a.Frecency(now), b.Frecency(now))
+	})
+	return visits
+}
+
+// Prune removes every visit for which keep returns false and returns how many
+// were removed.
+func (h *History) Prune(keep func(Visit) bool) int {
+	before := len(h.Visits)
+	h.Visits = slices.DeleteFunc(h.Visits, func(v Visit) bool { return !keep(v) })
+	return before - len(h.Visits)
+}
+
+// Scores returns the frecency of every path in the history.
+func (h History) Scores(now time.Time) map[string]float64 {
+	scores := make(map[string]float64, len(h.Visits))
+	for _, v := range h.Visits {
+		scores[v.Path] = v.Frecency(now)
+	}
+	return scores
+}
+
+// RankEntries orders entries by their frecency scores, highest first. Entries
+// with equal scores, such as ones that were never opened, keep their relative
+// order.
+func RankEntries(entries []model.Entry, scores map[string]float64) {
+	slices.SortStableFunc(entries, func(a, b model.Entry) int {
+		return compareScores(scores[a.Path], scores[b.Path])
+	})
+}
+
+// InsertRanked inserts entry into entries ranked by RankEntries, behind the
+// entries with the same score, and returns the result.
+func InsertRanked(entries []model.Entry, entry model.Entry, scores map[string]float64) []model.Entry {
+	score := scores[entry.Path]
+	i := len(entries)
+	for i > 0 && scores[entries[i-1].Path] < score {
+		i--
+	}
+	return slices.Insert(entries, i, entry)
+}
+
+// compareScores sorts higher scores first.
+func compareScores(a float64, b float64) int {
+	switch {
+	case a > b:
+		return -1
+	case a < b:
+		return 1
+	}
+	return 0
+}
+
+// recordOpen adds an open of the project at path to the history file.
+func recordOpen(label string, path string) error {
+	historyPath, err := HistoryPath()
+	if err != nil {
+		return err
+	}
+	history, err := LoadHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	history.Record(label, path, time.Now())
+	return history.Save(historyPath)
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/oschrenk/sessionizer/model"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var history History
+	// opened often, but a month ago
+	for range 5 {
+		history.Record("old", "/p/old", now.Add(-30*24*time.Hour))
+	}
+	// opened twice, the last time just now
+	history.Record("recent", "/p/recent", now.Add(-48*time.Hour))
+	history.Record("recent", "/p/recent", now.Add(-time.Minute))
+	// opened once, earlier today
+	history.Record("daily", "/p/daily", now.Add(-2*time.Hour))
+
+	if got := history.Frecency("/p/old", now); got != 1.25 {
+		t.Errorf("Frecency(old) = %v, want 1.25", got)
+	}
+	if got := history.Frecency("/p/recent", now); got != 8 {
+		t.Errorf("Frecency(recent) = %v, want 8", got)
+	}
+	if got := history.Frecency("/p/never", now); got != 0 {
+		t.Errorf("Frecency(never) = %v, want 0", got)
+	}
+
+	entries := []model.Entry{
+		{Label: "a", Path: "/p/a"},
+		{Label: "old", Path: "/p/old"},
+		{Label: "b", Path: "/p/b"},
+		{Label: "daily", Path: "/p/daily"},
+		{Label: "recent", Path: "/p/recent"},
+	}
+	RankEntries(entries, history.Scores(now))
+	labels := []string{}
+	for _, e := range entries {
+		labels = append(labels, e.Label)
+	}
+	if want := []string{"recent", "daily", "old", "a", "b"}; !slices.Equal(labels, want) {
+		t.Errorf("RankEntries() = %v, want %v", labels, want)
+	}
+
+	// projects streaming in after ranking take their place among the ranked
+	// ones, new projects go to the end
+	scores := history.Scores(now)
+	for _, entry := range []model.Entry{{Label: "c", Path: "/p/c"}, {Label: "daily2", Path: "/p/daily"}} {
+		entries = InsertRanked(entries, entry, scores)
+	}
+	labels = labels[:0]
+	for _, e := range entries {
+		labels = append(labels, e.Label)
+	}
+	if want := []string{"recent", "daily", "daily2", "old", "a", "b", "c"}; !slices.Equal(labels, want) {
+		t.Errorf("InsertRanked() = %v, want %v", labels, want)
+	}
+
+	removed := history.Prune(func(v Visit) bool { return v.Path != "/p/old" })
+	if removed != 1 || len(history.Visits) != 2 {
+		t.Errorf("Prune() removed %d, left %d visits, want 1 and 2", removed, len(history.Visits))
+	}
+
+	historyPath := filepath.Join(t.TempDir(), historyFileName)
+	empty, err := LoadHistory(historyPath)
+	if err != nil || len(empty.Visits) != 0 {
+		t.Fatalf("LoadHistory(missing) = %v, %v, want empty history", empty, err)
+	}
+	if err := history.Save(historyPath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHistory(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Frecency("/p/recent", now); got != 8 {
+		t.Errorf("loaded Frecency(recent) = %v, want 8", got)
+	}
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/oschrenk/sessionizer/internal/xdg"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+// indexVersion is bumped whenever the on-disk format or the scan semantics
+// change, invalidating every existing index.
+const indexVersion = 2
+
+const indexFileName = "index.json"
+
+// Index is the on-disk cache of the projects discovered in the search directories.
+type Index struct {
+	Version int `json:"version"`
+	// Fingerprint identifies the configuration the index was built with
+	Fingerprint string `json:"fingerprint"`
+	// Dirs maps every directory the scan descended into, and every ignore
+	// file and worktree metadata it read, to its modification time. Creating
+	// or removing a project always touches one of them.
+	Dirs    map[string]int64 `json:"dirs"`
+	Entries []model.Entry    `json:"entries"`
+}
+
+// IndexPath returns the location of the index in the XDG cache directory.
+func IndexPath() (string, error) {
+	dir, err := xdg.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, indexFileName), nil
+}
+
+// fingerprint hashes the configuration that influences the scan result.
+func fingerprint(config model.Config) string {
+	data, _ := json.Marshal(struct {
+		SearchDirs           []model.SearchDir
+		Ignore               []string
+		IgnoreFiles          bool
+		RooterPatterns       []string
+		NestedRooterPatterns []string
+		Worktrees            bool
+	}{config.SearchDirs, config.Ignore, config.IgnoreFiles, config.RooterPatterns, config.NestedRooterPatterns, config.Worktrees})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// modTime returns the modification time of path in nanoseconds, or 0 if it
+// cannot be read.
+func modTime(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// BuildIndex scans all search directories and returns a fresh index.
+func BuildIndex(config model.Config) (Index, error) {
+	return buildIndex(config, nil), nil
+}
+
+// buildIndex scans all search directories, passing every project to emit as
+// soon as it is found.
+func buildIndex(config model.Config, emit func(model.Entry)) Index {
+	index := Index{
+		Version:     indexVersion,
+		Fingerprint: fingerprint(config),
+		Dirs:        map[string]int64{},
+		Entries:     []model.Entry{},
+	}
+
+	for _, dirProjects := range walkDirs(config, index.Dirs, emit) {
+		index.Entries = append(index.Entries, dirProjects...)
+	}
+
+	return index
+}
+
+// LoadIndex reads an index from path.
+func LoadIndex(path string) (Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Index{}, err
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return Index{}, err
+	}
+	return index, nil
+}
+
+// Save writes the index to path, replacing any previous index atomically.
+func (index Index) Save(path string) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so concurrent readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Matches reports whether the index was built by this version for config.
+func (index Index) Matches(config model.Config) bool {
+	return index.Version == indexVersion && index.Fingerprint == fingerprint(config)
+}
+
+// Stale reports whether any directory recorded in the index has changed since
+// the index was built.
+func (index Index) Stale() bool {
+	for dir, mtime := range index.Dirs {
+		if modTime(dir) != mtime {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexedEntries returns the entries that are available right away: the
+// default entry, the specific search entries and, if the index at indexPath
+// matches config, the cached projects.
+//
+// The search directories are scanned in the background unless the cached
+// index is still fresh, which is revalidated by directory mtimes. Every project
+// found by that scan and not already returned is passed to emit; calls are
+// never concurrent. The scan result replaces the index at indexPath, unless
+// indexPath is empty. When rebuild is set, the cached index is not used.
+//
+// The returned channel receives the outcome of the background work and is
+// closed once it is done.
+func IndexedEntries(config model.Config, indexPath string, rebuild bool, emit func(model.Entry)) ([]model.Entry, <-chan error) {
+	var cached *Index
+	if !rebuild && indexPath != "" {
+		if index, err := LoadIndex(indexPath); err == nil && index.Matches(config) {
+			cached = &index
+		}
+	}
+
+	known := []model.Entry{}
+	seen := map[string]bool{}
+	if cached != nil {
+		known = cached.Entries
+		for _, entry := range known {
+			seen[entry.Path] = true
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		if cached != nil && !cached.Stale() {
+			return
+		}
+
+		index := buildIndex(config, func(entry model.Entry) {
+			if !seen[entry.Path] && emit != nil {
+				emit(entry)
+			}
+		})
+		if indexPath != "" {
+			done <- index.Save(indexPath)
+		}
+	}()
+
+	return assembleEntries(config, known), done
+}
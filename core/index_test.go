@@ -0,0 +1,127 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/oschrenk/sessionizer/model"
+)
+
+func TestIndex(t *testing.T) {
+	root := t.TempDir()
+	mkdirs := func(t *testing.T, paths ...string) {
+		for _, p := range paths {
+			if err := os.MkdirAll(filepath.Join(root, p), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	mkdirs(t, "org/repo/.git", "org/other")
+
+	config := model.Config{
+		SearchDirs:     []model.SearchDir{{Path: root}},
+		RooterPatterns: []string{".git"},
+	}
+
+	index, err := BuildIndex(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Entries) != 1 || index.Entries[0].Label != "org/repo" {
+		t.Fatalf("BuildIndex() entries = %v, want [org/repo]", index.Entries)
+	}
+	if !index.Matches(config) {
+		t.Error("Matches() = false for the config the index was built with")
+	}
+	if index.Matches(model.Config{SearchDirs: []model.SearchDir{{Path: root}}}) {
+		t.Error("Matches() = true for a different config")
+	}
+
+	indexPath := filepath.Join(t.TempDir(), "cache", indexFileName)
+	if err := index.Save(indexPath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Stale() {
+		t.Error("Stale() = true for an untouched tree")
+	}
+
+	mkdirs(t, "org/other/new/.git")
+	// mtimes can have coarse resolution, make the change observable
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(filepath.Join(root, "org/other"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Stale() {
+		t.Error("Stale() = false after a project was added")
+	}
+
+	// a project whose rooter pattern is removed is no project anymore
+	if index, err = BuildIndex(config); err != nil {
+		t.Fatal(err)
+	}
+	if index.Stale() {
+		t.Fatal("Stale() = true for a rebuilt index")
+	}
+	if err := os.RemoveAll(filepath.Join(root, "org/repo/.git")); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Second)
+	if err := os.Chtimes(filepath.Join(root, "org/repo"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !index.Stale() {
+		t.Error("Stale() = false after a rooter pattern was removed")
+	}
+}
+
+func TestIndexedEntries(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "repo/.git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := model.Config{
+		DefaultName:    "default",
+		SearchDirs:     []model.SearchDir{{Path: root}},
+		SearchEntries:  []model.SearchEntry{{Path: "/tmp/notes"}},
+		RooterPatterns: []string{".git"},
+	}
+	indexPath := filepath.Join(t.TempDir(), indexFileName)
+
+	collect := func(t *testing.T, rebuild bool) (immediate []string, streamed []string) {
+		entries, done := IndexedEntries(config, indexPath, rebuild, func(e model.Entry) {
+			streamed = append(streamed, e.Label)
+		})
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			immediate = append(immediate, e.Label)
+		}
+		return immediate, streamed
+	}
+
+	// without an index, projects are streamed behind the fixed entries
+	immediate, streamed := collect(t, false)
+	if !slices.Equal(immediate, []string{"default", "notes"}) || !slices.Equal(streamed, []string{"repo"}) {
+		t.Errorf("no index: immediate = %v, streamed = %v", immediate, streamed)
+	}
+
+	// a fresh index is returned right away and nothing is streamed
+	immediate, streamed = collect(t, false)
+	if !slices.Equal(immediate, []string{"default", "repo", "notes"}) || len(streamed) != 0 {
+		t.Errorf("fresh index: immediate = %v, streamed = %v", immediate, streamed)
+	}
+
+	// rebuild ignores the index
+	immediate, streamed = collect(t, true)
+	if !slices.Equal(immediate, []string{"default", "notes"}) || !slices.Equal(streamed, []string{"repo"}) {
+		t.Errorf("rebuild: immediate = %v, streamed = %v", immediate, streamed)
+	}
+}
@@ -1,116 +1,307 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/oschrenk/sessionizer/internal/shell"
 	"github.com/oschrenk/sessionizer/internal/tmux"
 	"github.com/oschrenk/sessionizer/internal/tmuxp"
 )
 
-// applyWindowLayout configures a single window according to its layout specification
-func applyWindowLayout(server *tmux.Server, windowId string, initialPaneId string, layoutWindow tmuxp.Window, sessionPath string) error {
-	// Rename window if name is specified in layout
-	if layoutWindow.Name != "" {
-		if err := server.RenameWindow(windowId, layoutWindow.Name); err != nil {
-			return fmt.Errorf("rename window: %w", err)
-		}
+// LayoutError reports where applying a layout failed. Window and Pane are
+// positions in the layout, -1 if the error is not about a single one.
+type LayoutError struct {
+	Window int
+	Pane   int
+	Err    error
+}
+
+func (e *LayoutError) Error() string {
+	switch {
+	case e.Window < 0:
+		return fmt.Sprintf("layout: %v", e.Err)
+	case e.Pane < 0:
+		return fmt.Sprintf("layout window %d: %v", e.Window, e.Err)
+	default:
+		return fmt.Sprintf("layout window %d pane %d: %v", e.Window, e.Pane, e.Err)
 	}
+}
 
-	// HACK: Fish shell (and potentially other shells) send terminal capability
-	// queries (like ^[[?997;1n for bracketed paste mode) during initialization.
-	// These escape sequences can appear in the terminal if we send commands too
-	// quickly. We wait for the shell to finish initializing, then clear the
-	// screen to remove any visible escape sequences before sending actual commands.
-	time.Sleep(200 * time.Millisecond)
-	server.SendKeys(initialPaneId, "clear")
-	time.Sleep(50 * time.Millisecond)
+func (e *LayoutError) Unwrap() error {
+	return e.Err
+}
 
-	// Change directory in first pane
-	// Use pane's start_directory if set, otherwise window's start_directory, otherwise session path
-	firstPaneDir := layoutWindow.Panes[0].StartDirectory
-	if firstPaneDir == "" {
-		firstPaneDir = layoutWindow.StartDirectory
+// checkDirectory returns an error unless dir is an existing directory. tmux
+// silently falls back to another directory otherwise.
+func checkDirectory(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
 	}
-	if firstPaneDir == "" {
-		firstPaneDir = sessionPath
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
 	}
-	if firstPaneDir != "" {
-		cdCmd := fmt.Sprintf("cd '%s'", firstPaneDir)
-		if err := server.SendKeys(initialPaneId, cdCmd); err != nil {
-			return fmt.Errorf("cd to start directory: %w", err)
+	return nil
+}
+
+// paneDirectories returns the start directory of every pane of window w: the
+// pane's start_directory if set, otherwise the window's, otherwise the session
+// path.
+func paneDirectories(w int, layoutWindow tmuxp.Window, sessionPath string) ([]string, error) {
+	paneDirs := make([]string, len(layoutWindow.Panes))
+	for i, pane := range layoutWindow.Panes {
+		paneDirs[i] = pane.StartDirectory
+		if paneDirs[i] == "" {
+			paneDirs[i] = layoutWindow.StartDirectory
+		}
+		if paneDirs[i] == "" {
+			paneDirs[i] = sessionPath
+		}
+		if err := checkDirectory(paneDirs[i]); err != nil {
+			return nil, &LayoutError{Window: w, Pane: i, Err: fmt.Errorf("start directory: %w", err)}
 		}
 	}
+	return paneDirs, nil
+}
 
-	// Track pane IDs for focus selection
-	paneIds := []string{initialPaneId}
-	var focusedPaneId string
-	if layoutWindow.Panes[0].Focus {
-		focusedPaneId = initialPaneId
+// launchCommands splits the commands of the panes by how they are run: panes
+// started as processes get their commands on creation, all others have them
+// typed into their shell as keys.
+func launchCommands(commands [][]string, launch []tmuxp.LaunchMode) (keys [][]string, processes []string) {
+	keys = make([][]string, len(commands))
+	processes = make([]string, len(commands))
+	for i := range commands {
+		if launch[i] == tmuxp.LaunchProcess && len(commands[i]) > 0 {
+			processes[i] = strings.Join(commands[i], "; ")
+		} else {
+			keys[i] = commands[i]
+		}
 	}
+	return keys, processes
+}
 
-	// Split window and create additional panes
-	for i := 1; i < len(layoutWindow.Panes); i++ {
-		// Use pane's start_directory if set, otherwise window's start_directory, otherwise session path
-		paneDir := layoutWindow.Panes[i].StartDirectory
-		if paneDir == "" {
-			paneDir = layoutWindow.StartDirectory
+// splitPanes creates the panes of window w following the ones in paneIds, by
+// splitting the target pane, the first one unless the layout says otherwise.
+// It returns the IDs of all panes.
+func splitPanes(server *tmux.Server, w int, paneIds []string, layoutWindow tmuxp.Window, paneDirs []string, processes []string) ([]string, error) {
+	for i := len(paneIds); i < len(layoutWindow.Panes); i++ {
+		pane := layoutWindow.Panes[i]
+		options := tmux.SplitOptions{Direction: tmux.Horizontal, StartDirectory: paneDirs[i], Size: pane.Size, Command: processes[i]}
+		if pane.Split == tmuxp.SplitVertical {
+			options.Direction = tmux.Vertical
 		}
-		if paneDir == "" {
-			paneDir = sessionPath
-		}
-
-		newPaneId, err := server.SplitPane(initialPaneId, tmux.Horizontal, paneDir)
+		newPaneId, err := server.SplitPane(paneIds[pane.Target], options)
 		if err != nil {
-			return fmt.Errorf("split pane %d: %w", i, err)
+			return nil, &LayoutError{Window: w, Pane: i, Err: fmt.Errorf("split pane: %w", err)}
 		}
 		paneIds = append(paneIds, newPaneId)
+	}
+	return paneIds, nil
+}
 
-		if layoutWindow.Panes[i].Focus {
-			focusedPaneId = newPaneId
+// typeCommands sends the keys of each pane of window w.
+func typeCommands(server *tmux.Server, w int, paneIds []string, keys [][]string) error {
+	// Keys typed while a shell starts up can get lost, or mixed with the
+	// escape sequences some shells (like fish) send during initialization,
+	// so wait for every pane that gets keys until its shell is ready
+	waiting := []string{}
+	for i, paneKeys := range keys {
+		if len(paneKeys) > 0 {
+			waiting = append(waiting, paneIds[i])
 		}
+	}
+	waitForShells(server, waiting)
 
-		// HACK: Same as above - wait for shell initialization and clear escape sequences
-		time.Sleep(200 * time.Millisecond)
-		server.SendKeys(newPaneId, "clear")
-		time.Sleep(50 * time.Millisecond)
+	for i, paneKeys := range keys {
+		for _, cmd := range paneKeys {
+			if err := server.SendKeys(paneIds[i], cmd); err != nil {
+				return &LayoutError{Window: w, Pane: i, Err: fmt.Errorf("send keys: %w", err)}
+			}
+		}
+	}
+	return nil
+}
+
+// applyWindowLayout configures window w according to its layout specification,
+// commands holds the commands of each pane and launch how they are run
+func applyWindowLayout(server *tmux.Server, w int, windowId string, initialPaneId string, layoutWindow tmuxp.Window, commands [][]string, launch []tmuxp.LaunchMode, sessionPath string) error {
+	// Rename window if name is specified in layout
+	if layoutWindow.Name != "" {
+		if err := server.RenameWindow(windowId, layoutWindow.Name); err != nil {
+			return &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("rename window: %w", err)}
+		}
+	}
+
+	paneDirs, err := paneDirectories(w, layoutWindow, sessionPath)
+	if err != nil {
+		return err
+	}
+	keys, processes := launchCommands(commands, launch)
+
+	// The first pane already runs a shell in the session or window directory,
+	// replace it by the process
+	if processes[0] != "" {
+		if err := server.RespawnPane(initialPaneId, paneDirs[0], processes[0]); err != nil {
+			return &LayoutError{Window: w, Pane: 0, Err: fmt.Errorf("respawn pane: %w", err)}
+		}
+	}
+
+	paneIds, err := splitPanes(server, w, []string{initialPaneId}, layoutWindow, paneDirs, processes)
+	if err != nil {
+		return err
 	}
 
 	// Apply window layout type if specified
 	if layoutWindow.Layout != "" {
 		if err := server.SelectLayout(windowId, string(layoutWindow.Layout)); err != nil {
-			return fmt.Errorf("select layout: %w", err)
+			return &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("select layout: %w", err)}
 		}
 	}
 
-	// Send shell commands to panes
+	// The first pane was not created with its start directory, change to it
+	// before running anything else
+	if processes[0] == "" && paneDirs[0] != "" {
+		keys[0] = append([]string{"cd " + shell.Quote(paneDirs[0])}, keys[0]...)
+	}
+
+	if err := typeCommands(server, w, paneIds, keys); err != nil {
+		return err
+	}
+
+	// Select the focused pane
 	for i, pane := range layoutWindow.Panes {
-		if len(pane.ShellCommand) > 0 {
-			// Join all command arguments into a single string
-			cmd := strings.Join(pane.ShellCommand, " ")
-			if err := server.SendKeys(paneIds[i], cmd); err != nil {
-				return fmt.Errorf("send keys to pane %d: %w", i, err)
+		if pane.Focus {
+			if err := server.SelectPane(paneIds[i]); err != nil {
+				return &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("select pane: %w", err)}
 			}
 		}
 	}
 
-	// Select the focused pane
-	if focusedPaneId != "" {
-		if err := server.SelectPane(focusedPaneId); err != nil {
-			return fmt.Errorf("select pane: %w", err)
+	return nil
+}
+
+// paneCommands returns the commands to run in pane i of window w: the
+// shell_command_before of the layout and of the window, followed by the
+// pane's own shell commands, each one on its own like tmuxp does. Commands
+// typed into a shell and kept out of its history start with a space.
+func paneCommands(layout tmuxp.Layout, w int, i int) []string {
+	window := layout.Windows[w]
+	pane := window.Panes[i]
+
+	commands := append(slices.Clone(layout.ShellCommandBefore), window.ShellCommandBefore...)
+	commands = append(commands, pane.ShellCommand...)
+	if layout.Suppress(w, i) && layout.PaneLaunch(w, i) == tmuxp.LaunchKeys {
+		for j := range commands {
+			commands[j] = " " + commands[j]
 		}
 	}
+	return commands
+}
 
+// runBeforeScript runs the before_script of a layout in the session directory,
+// a relative script path is resolved from there as well.
+func runBeforeScript(script string, sessionPath string) error {
+	if !filepath.IsAbs(script) {
+		script = filepath.Join(sessionPath, script)
+	}
+	_, stderr, err := shell.RunIn(sessionPath, script, nil)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
 	return nil
 }
 
+// windowCommands returns the commands of every pane of window w, and how they
+// are run.
+func windowCommands(layout tmuxp.Layout, w int) ([][]string, []tmuxp.LaunchMode) {
+	panes := layout.Windows[w].Panes
+	commands := make([][]string, len(panes))
+	launch := make([]tmuxp.LaunchMode, len(panes))
+	for i := range panes {
+		commands[i] = paneCommands(layout, w, i)
+		launch[i] = layout.PaneLaunch(w, i)
+	}
+	return commands, launch
+}
+
+// addWindow creates window w of the layout in the session and returns its ID
+// and the ID of its first pane.
+func addWindow(server *tmux.Server, session tmux.Session, layoutWindow tmuxp.Window, w int) (string, string, error) {
+	// Use window's start_directory if set, otherwise use session path
+	windowDir := layoutWindow.StartDirectory
+	if windowDir == "" {
+		windowDir = session.Path
+	}
+	if err := checkDirectory(windowDir); err != nil {
+		return "", "", &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("start directory: %w", err)}
+	}
+
+	target := session.Id + ":"
+	if layoutWindow.Index != nil {
+		target += strconv.Itoa(*layoutWindow.Index)
+	}
+	windowId, err := server.AddWindow(target, layoutWindow.Name, windowDir)
+	if err != nil {
+		return "", "", &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("create window: %w", err)}
+	}
+
+	// Get the initial pane ID of the newly created window
+	panes, err := server.ListPanes(windowId)
+	if err != nil {
+		return "", "", &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("list panes: %w", err)}
+	}
+	if len(panes) == 0 {
+		return "", "", &LayoutError{Window: w, Pane: -1, Err: errors.New("could not find pane for new window")}
+	}
+	return windowId, panes[0].Id, nil
+}
+
+// configureWindow sets the options of window w and sets up its panes.
+func configureWindow(server *tmux.Server, session tmux.Session, layout tmuxp.Layout, w int, windowId string, initialPaneId string) error {
+	layoutWindow := layout.Windows[w]
+	for _, key := range slices.Sorted(maps.Keys(layoutWindow.Options)) {
+		if err := server.SetWindowOption(windowId, key, layoutWindow.Options[key]); err != nil {
+			return &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("set option %s: %w", key, err)}
+		}
+	}
+
+	commands, launch := windowCommands(layout, w)
+	return applyWindowLayout(server, w, windowId, initialPaneId, layoutWindow, commands, launch, session.Path)
+}
+
 // ApplyLayout applies a tmuxp layout configuration
 //
-// Supports multiple windows with multiple panes
+// Supports multiple windows with multiple panes. The environment and the
+// session name of the layout are expected to be set when creating the session.
+// Errors are *LayoutError, saying which window and pane failed.
 func ApplyLayout(server *tmux.Server, initialSession tmux.Session, layout tmuxp.Layout) error {
-	// Track the first window ID to return focus at the end
-	firstWindowId := initialSession.Windows[0].Id
+	if plan := server.Plan(); plan != nil && layout.BeforeScript != "" {
+		// a dry run must not change anything, the script is only listed
+		plan.Add(layout.BeforeScript)
+	} else if layout.BeforeScript != "" {
+		if err := runBeforeScript(layout.BeforeScript, initialSession.Path); err != nil {
+			return &LayoutError{Window: -1, Pane: -1, Err: fmt.Errorf("before_script: %w", err)}
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(layout.Options)) {
+		if err := server.SetOption(initialSession.Id, key, layout.Options[key]); err != nil {
+			return &LayoutError{Window: -1, Pane: -1, Err: fmt.Errorf("set option %s: %w", key, err)}
+		}
+	}
+
+	// Focus returns to the first window at the end, unless another one asks for it
+	focusedWindowId := initialSession.Windows[0].Id
 
 	// Configure each window in the layout
 	for i, layoutWindow := range layout.Windows {
@@ -121,48 +312,40 @@ func ApplyLayout(server *tmux.Server, initialSession tmux.Session, layout tmuxp.
 			// Use the existing initial window for the first layout window
 			windowId = initialSession.Windows[0].Id
 			initialPaneId = initialSession.Windows[0].Panes[0].Id
-		} else {
-			// Create a new window for additional layout windows
-			// Use window's start_directory if set, otherwise use session path
-			windowDir := layoutWindow.StartDirectory
-			if windowDir == "" {
-				windowDir = initialSession.Path
-			}
 
-			newWindowId, err := server.AddWindow(layoutWindow.Name, windowDir)
-			if err != nil {
-				return fmt.Errorf("create window %d: %w", i, err)
-			}
-			windowId = newWindowId
-
-			// Get the initial pane ID of the newly created window
-			windows, err := server.ListWindows(initialSession.Id)
-			if err != nil {
-				return fmt.Errorf("list windows: %w", err)
-			}
-			// Find the window we just created
-			for _, w := range windows {
-				if w.Id == newWindowId {
-					if len(w.Panes) > 0 {
-						initialPaneId = w.Panes[0].Id
-					}
-					break
+			if layoutWindow.Index != nil {
+				target := fmt.Sprintf("%s:%d", initialSession.Id, *layoutWindow.Index)
+				if err := server.MoveWindow(windowId, target); err != nil {
+					return &LayoutError{Window: i, Pane: -1, Err: fmt.Errorf("move window: %w", err)}
 				}
 			}
-			if initialPaneId == "" {
-				return fmt.Errorf("could not find pane for new window %d", i)
+		} else {
+			var err error
+			windowId, initialPaneId, err = addWindow(server, initialSession, layoutWindow, i)
+			if err != nil {
+				return err
 			}
 		}
 
-		// Apply the layout configuration to this window
-		if err := applyWindowLayout(server, windowId, initialPaneId, layoutWindow, initialSession.Path); err != nil {
-			return fmt.Errorf("apply window %d layout: %w", i, err)
+		if err := configureWindow(server, initialSession, layout, i, windowId, initialPaneId); err != nil {
+			return err
+		}
+
+		if layoutWindow.Focus {
+			focusedWindowId = windowId
+		}
+	}
+
+	// global options outlive the session, they are only set once it could be
+	// set up, so that killing a failed session undoes all of it
+	for _, key := range slices.Sorted(maps.Keys(layout.GlobalOptions)) {
+		if err := server.SetOption("", key, layout.GlobalOptions[key]); err != nil {
+			return &LayoutError{Window: -1, Pane: -1, Err: fmt.Errorf("set global option %s: %w", key, err)}
 		}
 	}
 
-	// Return focus to the first window
-	if err := server.SelectWindow(firstWindowId); err != nil {
-		return fmt.Errorf("select first window: %w", err)
+	if err := server.SelectWindow(focusedWindowId); err != nil {
+		return &LayoutError{Window: -1, Pane: -1, Err: fmt.Errorf("select window: %w", err)}
 	}
 
 	return nil
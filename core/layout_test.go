@@ -0,0 +1,147 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+)
+
+func TestPaneCommands(t *testing.T) {
+	suppress := true
+	keep := false
+	layout := tmuxp.Layout{
+		ShellCommandBefore: []string{"source .env"},
+		SuppressHistory:    &suppress,
+		Windows: []tmuxp.Window{
+			{ShellCommandBefore: []string{"nvm use"}, Panes: []tmuxp.Pane{
+				{ShellCommand: []string{"git fetch", "git status"}},
+				{SuppressHistory: &keep},
+			}},
+		},
+	}
+
+	if got, want := paneCommands(layout, 0, 0), []string{" source .env", " nvm use", " git fetch", " git status"}; !slices.Equal(got, want) {
+		t.Errorf("paneCommands(0, 0) = %q, want %q", got, want)
+	}
+	if got, want := paneCommands(layout, 0, 1), []string{"source .env", "nvm use"}; !slices.Equal(got, want) {
+		t.Errorf("paneCommands(0, 1) = %q, want %q", got, want)
+	}
+	if len(layout.ShellCommandBefore) != 1 || layout.ShellCommandBefore[0] != "source .env" {
+		t.Errorf("paneCommands() modified the layout: %q", layout.ShellCommandBefore)
+	}
+
+	// processes are not typed into a shell, so there is no history to keep
+	// them out of
+	layout.Windows[0].Panes[0].Launch = tmuxp.LaunchProcess
+	if got, want := paneCommands(layout, 0, 0), []string{"source .env", "nvm use", "git fetch", "git status"}; !slices.Equal(got, want) {
+		t.Errorf("paneCommands(0, 0) = %q, want %q", got, want)
+	}
+}
+
+func TestRunBeforeScript(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\ntouch ran\n"
+	if err := os.WriteFile(filepath.Join(dir, "bootstrap.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	failing := "#!/bin/sh\necho broken >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "failing.sh"), []byte(failing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runBeforeScript("./bootstrap.sh", dir); err != nil {
+		t.Fatalf("runBeforeScript() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err != nil {
+		t.Errorf("runBeforeScript() did not run in the session directory: %v", err)
+	}
+	if err := runBeforeScript("failing.sh", dir); err == nil {
+		t.Errorf("runBeforeScript() ignored a failing script")
+	}
+}
+
+func TestLayoutError(t *testing.T) {
+	err := errors.New("no space for new pane")
+	tests := []struct {
+		err  *LayoutError
+		want string
+	}{
+		{&LayoutError{Window: -1, Pane: -1, Err: err}, "layout: no space for new pane"},
+		{&LayoutError{Window: 1, Pane: -1, Err: err}, "layout window 1: no space for new pane"},
+		{&LayoutError{Window: 1, Pane: 2, Err: err}, "layout window 1 pane 2: no space for new pane"},
+	}
+	for _, test := range tests {
+		if got := test.err.Error(); got != test.want {
+			t.Errorf("Error() = %q, want %q", got, test.want)
+		}
+		if !errors.Is(test.err, err) {
+			t.Errorf("errors.Is(%v) = false, want the wrapped error", test.err)
+		}
+	}
+}
+
+func TestCheckDirectory(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkDirectory(dir); err != nil {
+		t.Errorf("checkDirectory(dir) error: %v", err)
+	}
+	if err := checkDirectory(file); err == nil {
+		t.Errorf("checkDirectory(file) accepted a file")
+	}
+	if err := checkDirectory(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("checkDirectory(missing) accepted a missing directory")
+	}
+}
+
+func TestApplyLayoutGlobalOptions(t *testing.T) {
+	dir := t.TempDir()
+	layout := tmuxp.Layout{
+		GlobalOptions: map[string]string{"mouse": "on"},
+		Windows: []tmuxp.Window{
+			{Panes: []tmuxp.Pane{{}}},
+			{StartDirectory: filepath.Join(dir, "missing"), Panes: []tmuxp.Pane{{}}},
+		},
+	}
+	globalOption := func(plan *tmux.Plan) int {
+		return slices.IndexFunc(plan.Commands, func(command []string) bool {
+			return slices.Contains(command, "set-option") && slices.Contains(command, "-g")
+		})
+	}
+
+	// global options outlive the session, a failing layout must not set them
+	plan := new(tmux.Plan)
+	server := tmux.DryRun(plan)
+	session, err := server.AddSession("app", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyLayout(server, session, layout); err == nil {
+		t.Fatal("ApplyLayout() accepted a missing start directory")
+	}
+	if i := globalOption(plan); i >= 0 {
+		t.Errorf("failed ApplyLayout() set a global option: %q", plan.Commands[i])
+	}
+
+	layout.Windows[1].StartDirectory = dir
+	plan = new(tmux.Plan)
+	server = tmux.DryRun(plan)
+	if session, err = server.AddSession("app", dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyLayout(server, session, layout); err != nil {
+		t.Fatalf("ApplyLayout() error: %v", err)
+	}
+	if i := globalOption(plan); i < 0 {
+		t.Errorf("ApplyLayout() did not set the global option: %q", plan.Commands)
+	}
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/ktr0731/go-fuzzyfinder/matching"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+// Match finds the entries a query refers to: the entries whose label equals
+// the query, else the ones whose label equals it ignoring case, else all
+// entries whose label fuzzy matches it, best match first, as in the finder.
+// Entries with the same path count once. A single result is a unique match.
+func Match(entries []model.Entry, query string) []model.Entry {
+	exact := []model.Entry{}
+	folded := []model.Entry{}
+	for _, entry := range entries {
+		if entry.Label == query {
+			exact = append(exact, entry)
+		} else if strings.EqualFold(entry.Label, query) {
+			folded = append(folded, entry)
+		}
+	}
+	if len(exact) > 0 {
+		return uniquePaths(exact)
+	}
+	if len(folded) > 0 {
+		return uniquePaths(folded)
+	}
+
+	labels := make([]string, len(entries))
+	for i, entry := range entries {
+		labels[i] = entry.Label
+	}
+	fuzzy := []model.Entry{}
+	for _, m := range matching.FindAll(query, labels) {
+		fuzzy = append(fuzzy, entries[m.Idx])
+	}
+	return uniquePaths(fuzzy)
+}
+
+// uniquePaths drops entries whose path was already seen, keeping order.
+func uniquePaths(entries []model.Entry) []model.Entry {
+	seen := map[string]bool{}
+	result := []model.Entry{}
+	for _, entry := range entries {
+		if !seen[entry.Path] {
+			seen[entry.Path] = true
+			result = append(result, entry)
+		}
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/oschrenk/sessionizer/model"
+)
+
+func TestMatch(t *testing.T) {
+	entries := []model.Entry{
+		{Label: "default", Path: "/home/me"},
+		{Label: "org/app", Path: "/p/org/app"},
+		{Label: "org/app-web", Path: "/p/org/app-web"},
+		{Label: "Notes", Path: "/p/notes"},
+		{Label: "org/app", Path: "/p/org/app"},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "exact label wins over fuzzy", query: "org/app", want: []string{"/p/org/app"}},
+		{name: "label ignoring case", query: "notes", want: []string{"/p/notes"}},
+		{name: "unique fuzzy match", query: "aweb", want: []string{"/p/org/app-web"}},
+		{name: "ambiguous fuzzy match", query: "app", want: []string{"/p/org/app", "/p/org/app-web"}},
+		{name: "no match", query: "xyz", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Match(entries, tt.query)
+			paths := []string{}
+			for _, e := range got {
+				paths = append(paths, e.Path)
+			}
+			if len(paths) != len(tt.want) {
+				t.Fatalf("Match(%q) = %v, want %v", tt.query, paths, tt.want)
+			}
+			// fuzzy results are ordered by score, only compare the set
+			for _, w := range tt.want {
+				found := false
+				for _, p := range paths {
+					found = found || p == w
+				}
+				if !found {
+					t.Errorf("Match(%q) = %v, want %v", tt.query, paths, tt.want)
+				}
+			}
+		})
+	}
+}
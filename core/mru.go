@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/xdg"
+)
+
+const mruVersion = 1
+
+const mruFileName = "mru.json"
+
+// SessionUse records when a session was last used.
+type SessionUse struct {
+	Name     string    `json:"name"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// MRU is the stack of running sessions, most recently used first. It is kept
+// by sessionizer itself so that the order survives sessions being killed and
+// does not depend on which client switched.
+type MRU struct {
+	Version  int          `json:"version"`
+	Sessions []SessionUse `json:"sessions"`
+	// Cycle is the order taken when --next or --prev started cycling, so
+	// that switching does not reorder the sessions still to come.
+	// Position is the index of the session switched to last.
+	Cycle    []string `json:"cycle,omitempty"`
+	Position int      `json:"position,omitempty"`
+}
+
+// MRUPath returns the location of the stack in the XDG state directory.
+func MRUPath() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, mruFileName), nil
+}
+
+// LoadMRU reads the stack from path. A missing file is an empty stack.
+func LoadMRU(path string) (MRU, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return MRU{Version: mruVersion}, nil
+	}
+	if err != nil {
+		return MRU{}, err
+	}
+
+	var mru MRU
+	if err := json.Unmarshal(data, &mru); err != nil {
+		return MRU{}, err
+	}
+	return mru, nil
+}
+
+// Save writes the stack to path.
+func (m MRU) Save(path string) error {
+	m.Version = mruVersion
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// lastUsed returns when tmux saw the session used: when a client last
+// attached to it, or for a session no client ever attached to, its last
+// activity.
+func lastUsed(session tmux.Session) time.Time {
+	if session.LastAttached > 0 {
+		return time.Unix(session.LastAttached, 0)
+	}
+	return time.Unix(session.Activity, 0)
+}
+
+// Update merges the running sessions into the stack. A session takes the
+// later of its recorded use and the one tmux reports. Sessions that no longer
+// run are dropped, so the stack always leads to a live session.
+func (m *MRU) Update(sessions []tmux.Session) {
+	recorded := make(map[string]time.Time, len(m.Sessions))
+	for _, use := range m.Sessions {
+		recorded[use.Name] = use.LastUsed
+	}
+
+	uses := make([]SessionUse, 0, len(sessions))
+	for _, session := range sessions {
+		used := lastUsed(session)
+		if at, ok := recorded[session.Name]; ok && at.After(used) {
+			used = at
+		}
+		uses = append(uses, SessionUse{Name: session.Name, LastUsed: used})
+	}
+	slices.SortFunc(uses, func(a, b SessionUse) int {
+		if c := b.LastUsed.Compare(a.LastUsed); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	m.Sessions = uses
+}
+
+// Touch moves the session to the top of the stack.
+func (m *MRU) Touch(name string, at time.Time) {
+	m.Sessions = slices.DeleteFunc(m.Sessions, func(use SessionUse) bool { return use.Name == name })
+	m.Sessions = slices.Insert(m.Sessions, 0, SessionUse{Name: name, LastUsed: at})
+}
+
+// Names returns the sessions, most recently used first.
+func (m MRU) Names() []string {
+	names := make([]string, 0, len(m.Sessions))
+	for _, use := range m.Sessions {
+		names = append(names, use.Name)
+	}
+	return names
+}
+
+// running reports whether the session is on the stack, and so still runs.
+func (m MRU) running(name string) bool {
+	return slices.ContainsFunc(m.Sessions, func(use SessionUse) bool { return use.Name == name })
+}
+
+// Last returns the most recently used session other than current, and ends
+// any cycle.
+func (m *MRU) Last(current string) (string, bool) {
+	m.Cycle = nil
+	m.Position = 0
+	for _, use := range m.Sessions {
+		if use.Name != current {
+			return use.Name, true
+		}
+	}
+	return "", false
+}
+
+// Step returns the session delta places after current in the cycle, wrapping
+// around at either end and passing over sessions that no longer run. A new
+// cycle in stack order starts unless current is where the previous step went.
+func (m *MRU) Step(current string, delta int) (string, bool) {
+	if m.Position < 0 || m.Position >= len(m.Cycle) || m.Cycle[m.Position] != current {
+		m.Cycle = m.Names()
+		m.Position = slices.Index(m.Cycle, current)
+		if m.Position < 0 && delta < 0 {
+			// outside of any session, --next starts at the most recent
+			// session and --prev at the oldest
+			m.Position = 0
+		}
+	}
+
+	n := len(m.Cycle)
+	for range n {
+		m.Position = ((m.Position+delta)%n + n) % n
+		name := m.Cycle[m.Position]
+		if m.running(name) && name != current {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+)
+
+func TestMRU(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	sessions := []tmux.Session{
+		{Name: "a", LastAttached: now.Add(-time.Hour).Unix()},
+		{Name: "b", LastAttached: now.Add(-time.Minute).Unix()},
+		// never attached, ranked by its activity
+		{Name: "c", Activity: now.Add(-30 * time.Minute).Unix()},
+	}
+
+	var mru MRU
+	mru.Update(sessions)
+	if want := []string{"b", "c", "a"}; !slices.Equal(mru.Names(), want) {
+		t.Errorf("Update() = %v, want %v", mru.Names(), want)
+	}
+
+	// a recorded switch outranks the older time tmux reports
+	mru.Touch("a", now)
+	mru.Update(sessions)
+	if want := []string{"a", "b", "c"}; !slices.Equal(mru.Names(), want) {
+		t.Errorf("Update() after Touch(a) = %v, want %v", mru.Names(), want)
+	}
+
+	if got, ok := mru.Last("a"); !ok || got != "b" {
+		t.Errorf("Last(a) = %q, %v, want b", got, ok)
+	}
+
+	// cycling keeps the order it started with, even though every switch
+	// moves the target to the top of the stack
+	current := "a"
+	visited := []string{}
+	for range 3 {
+		next, ok := mru.Step(current, 1)
+		if !ok {
+			t.Fatalf("Step(%q, 1) found nothing", current)
+		}
+		mru.Touch(next, now)
+		visited = append(visited, next)
+		current = next
+	}
+	if want := []string{"b", "c", "a"}; !slices.Equal(visited, want) {
+		t.Errorf("Step(+1) visited %v, want %v", visited, want)
+	}
+	if got, _ := mru.Step("a", -1); got != "c" {
+		t.Errorf("Step(a, -1) = %q, want c", got)
+	}
+
+	// killed sessions are dropped and passed over
+	mru.Update(sessions[:2])
+	if got, _ := mru.Step("c", 1); got != "a" {
+		t.Errorf("Step() after killing c = %q, want a", got)
+	}
+	mru.Update(sessions[1:2])
+	if got, ok := mru.Last(""); !ok || got != "b" {
+		t.Errorf("Last() with only b running = %q, %v, want b", got, ok)
+	}
+	if got, ok := mru.Step("b", 1); ok {
+		t.Errorf("Step(b) with only b running = %q, want none", got)
+	}
+
+	mruPath := filepath.Join(t.TempDir(), mruFileName)
+	if err := mru.Save(mruPath); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	loaded, err := LoadMRU(mruPath)
+	if err != nil {
+		t.Fatalf("LoadMRU() error: %v", err)
+	}
+	if !slices.Equal(loaded.Names(), mru.Names()) {
+		t.Errorf("LoadMRU() = %v, want %v", loaded.Names(), mru.Names())
+	}
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oschrenk/sessionizer/internal/git"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+// previewReadmeLines is the number of README lines shown in a preview
+const previewReadmeLines = 40
+
+// readmeNames are the README files looked for, in order of preference
+var readmeNames = []string{"README.md", "README", "README.markdown", "README.txt", "readme.md"}
+
+// Preview describes an entry for the preview window of the finder: its path,
+// its running session, the layout it would start with, its git state and the
+// beginning of its README.
+func Preview(entry model.Entry, sessions []tmux.Session, configDir string) string {
+	return preview(entry, sessions, configDir, true)
+}
+
+// PendingPreview is Preview without checking the work tree for changes,
+// which runs git and can take a while in large repositories. It stands in
+// for Preview until that is done.
+func PendingPreview(entry model.Entry, sessions []tmux.Session, configDir string) string {
+	return preview(entry, sessions, configDir, false)
+}
+
+func preview(entry model.Entry, sessions []tmux.Session, configDir string, checkDirty bool) string {
+	var b strings.Builder
+
+	fmt.Fprintln(&b, entry.Path)
+	fmt.Fprintln(&b)
+
+	session := "not running"
+	if s := SessionFor(entry, sessions); s != nil {
+		state := "detached"
+		if s.Attached {
+			state = "attached"
+		}
+		names := make([]string, 0, len(s.Windows))
+		for _, w := range s.Windows {
+			names = append(names, w.Name)
+		}
+		session = fmt.Sprintf("%s, %d windows: %s", state, len(s.Windows), strings.Join(names, ", "))
+	}
+	fmt.Fprintf(&b, "session  %s\n", session)
+
+	layout := "none"
+	if resolved := resolveLayoutPath(entry.Path, entry.Layout, entry.LayoutPath, configDir); resolved != "" {
+		layout = resolved
+	}
+	fmt.Fprintf(&b, "layout   %s\n", layout)
+
+	repo := "not a repository"
+	if branch, err := git.Branch(entry.Path); err == nil {
+		repo = branch
+		if !checkDirty {
+			repo += " (checking for changes)"
+		} else if dirty, err := git.Dirty(entry.Path); err == nil && dirty {
+			repo += " (dirty)"
+		}
+	}
+	fmt.Fprintf(&b, "git      %s\n", repo)
+
+	if lines := readme(entry.Path, previewReadmeLines); len(lines) > 0 {
+		fmt.Fprintln(&b)
+		for _, line := range lines {
+			fmt.Fprintln(&b, line)
+		}
+	}
+
+	return b.String()
+}
+
+// readme returns up to n lines of the README in dir.
+func readme(dir string, n int) []string {
+	for _, name := range readmeNames {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+
+		lines := []string{}
+		scanner := bufio.NewScanner(f)
+		for len(lines) < n && scanner.Scan() {
+			// tabs are not expanded by the preview window
+			lines = append(lines, strings.ReplaceAll(scanner.Text(), "\t", "    "))
+		}
+		return lines
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+func TestPreview(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Project\n\tindented\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LayoutFileName), []byte("windows: []"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := model.Entry{Label: "Org/My.Project", Path: dir}
+	sessions := []tmux.Session{
+		{Name: "other"},
+		{Name: "org/my-project", Attached: true, Windows: []tmux.Window{{Name: "fish"}, {Name: "nvim"}}},
+	}
+
+	got := Preview(entry, sessions, t.TempDir())
+	for _, want := range []string{
+		dir + "\n",
+		"session  attached, 2 windows: fish, nvim\n",
+		"layout   " + filepath.Join(dir, LayoutFileName) + "\n",
+		"git      not a repository\n",
+		"# Project\n    indented\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Preview() = %q, missing %q", got, want)
+		}
+	}
+
+	if got := Preview(entry, nil, t.TempDir()); !strings.Contains(got, "session  not running\n") {
+		t.Errorf("Preview() without sessions = %q", got)
+	}
+	// the pending preview reads the branch, but doesn't run git for changes
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := PendingPreview(entry, sessions, t.TempDir()); !strings.Contains(got, "git      main (checking for changes)\n") {
+		t.Errorf("PendingPreview() = %q", got)
+	}
+}
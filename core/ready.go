@@ -0,0 +1,71 @@
+package core
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/util"
+)
+
+const (
+	// readyInterval is how often a pane is checked
+	readyInterval = 25 * time.Millisecond
+	// readyStable is how long the content of a pane must not change for
+	// its shell to count as ready
+	readyStable = 150 * time.Millisecond
+	// readyTimeout is how long to wait for a shell at most, keys are sent
+	// anyway afterwards
+	readyTimeout = 5 * time.Second
+)
+
+// waitUntilStable calls poll every interval until it returned the same,
+// non-blank output for at least stable. It gives up after timeout and reports
+// whether the output became stable. Failing polls count as blank output.
+func waitUntilStable(poll func() (string, error), interval time.Duration, stable time.Duration, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	var last string
+	var since time.Time
+	for {
+		out, err := poll()
+		now := time.Now()
+		switch {
+		case err != nil || strings.TrimSpace(out) == "":
+			last, since = "", time.Time{}
+		case out != last || since.IsZero():
+			last, since = out, now
+		case now.Sub(since) >= stable:
+			return true
+		}
+
+		if now.After(deadline) {
+			return false
+		}
+		time.Sleep(interval)
+	}
+}
+
+// waitForShells blocks until the shells in all panes have printed their prompt
+// and stopped writing, so that keys sent next are neither lost nor mixed with
+// the output of their startup. Panes are waited for concurrently.
+func waitForShells(server *tmux.Server, paneIds []string) {
+	// a dry run has no shells to wait for
+	if server.Plan() != nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, paneId := range paneIds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			poll := func() (string, error) { return server.CapturePane(paneId) }
+			if !waitUntilStable(poll, readyInterval, readyStable, readyTimeout) {
+				util.DebugLog("layout: shell in pane %s not ready after %s", paneId, readyTimeout)
+			}
+		}()
+	}
+	wg.Wait()
+}
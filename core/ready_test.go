@@ -0,0 +1,53 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// outputs returns a poll function returning the given outputs in turn,
+// repeating the last one
+func outputs(outs ...string) func() (string, error) {
+	i := 0
+	return func() (string, error) {
+		out := outs[min(i, len(outs)-1)]
+		i++
+		if out == "error" {
+			return "", errors.New("no such pane")
+		}
+		return out, nil
+	}
+}
+
+func TestWaitUntilStable(t *testing.T) {
+	tests := []struct {
+		name string
+		poll func() (string, error)
+		want bool
+	}{
+		{"prompt", outputs("", "$ "), true},
+		{"startup output", outputs("", "loading", "loading.", "loading..", "$ "), true},
+		{"blank", outputs("", "\n\n"), false},
+		{"error", outputs("error"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := waitUntilStable(test.poll, time.Millisecond, 5*time.Millisecond, 50*time.Millisecond)
+			if got != test.want {
+				t.Errorf("waitUntilStable() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWaitUntilStableChanging(t *testing.T) {
+	n := 0
+	poll := func() (string, error) {
+		n++
+		return string(rune('a' + n%26)), nil
+	}
+	if waitUntilStable(poll, time.Millisecond, 5*time.Millisecond, 30*time.Millisecond) {
+		t.Error("waitUntilStable() = true for output that keeps changing")
+	}
+}
@@ -0,0 +1,200 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+	"github.com/oschrenk/sessionizer/internal/util"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+// sessionEntry returns the entry a running session was started for: the one
+// named like the session, otherwise the one with the same path.
+func sessionEntry(session tmux.Session, entries []model.Entry) (model.Entry, bool) {
+	for _, entry := range entries {
+		if entry.Session == "" && tmux.NormalizeName(entry.Label) == session.Name {
+			return entry, true
+		}
+	}
+	for _, entry := range entries {
+		if entry.Session == "" && entry.Path == session.Path {
+			return entry, true
+		}
+	}
+	return model.Entry{}, false
+}
+
+// SessionLayoutPath returns the layout file of a running session, or "" if it
+// has none. The layout is resolved like StartSession does, from the entry the
+// session was started for.
+func SessionLayoutPath(session tmux.Session, entries []model.Entry, configDir string) string {
+	entry, _ := sessionEntry(session, entries)
+	return resolveLayoutPath(session.Path, entry.Layout, entry.LayoutPath, configDir)
+}
+
+// ReadSessionLayout reads the layout of a running session, a template with
+// the variables of the entry the session was started for. It returns the
+// layout and its file, a nil layout if the session has none.
+func ReadSessionLayout(session tmux.Session, entries []model.Entry, configDir string) (*tmuxp.Layout, string, error) {
+	layoutPath := SessionLayoutPath(session, entries, configDir)
+	if layoutPath == "" {
+		return nil, "", nil
+	}
+	label, vars := session.Name, map[string]string(nil)
+	if entry, found := sessionEntry(session, entries); found {
+		label, vars = entry.Label, entry.Vars
+	}
+	data := templateData(label, session.Path, vars)
+	data.Session = session.Name
+	layout, err := tmuxp.ReadLayoutTemplate(layoutPath, data)
+	return layout, layoutPath, err
+}
+
+// matchWindows pairs the windows of the layout with running windows, named ones
+// by name and unnamed ones by position. Layout windows without a running
+// window are -1.
+func matchWindows(running []tmux.Window, layoutWindows []tmuxp.Window) []int {
+	matched := make([]int, len(layoutWindows))
+	used := make([]bool, len(running))
+	for i, layoutWindow := range layoutWindows {
+		matched[i] = -1
+		if layoutWindow.Name == "" {
+			continue
+		}
+		for j, window := range running {
+			if !used[j] && window.Name == layoutWindow.Name {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i, layoutWindow := range layoutWindows {
+		if layoutWindow.Name == "" && i < len(running) && !used[i] {
+			matched[i], used[i] = i, true
+		}
+	}
+	return matched
+}
+
+// windowLabel names window w of the layout in messages.
+func windowLabel(layout tmuxp.Layout, w int) string {
+	if name := layout.Windows[w].Name; name != "" {
+		return fmt.Sprintf("window %d (%s)", w, name)
+	}
+	return fmt.Sprintf("window %d", w)
+}
+
+// addPanes splits off the panes of window w that the running window lacks and
+// runs their commands. The panes already there keep running what they run.
+func addPanes(server *tmux.Server, session tmux.Session, layout tmuxp.Layout, w int, window tmux.Window) error {
+	layoutWindow := layout.Windows[w]
+
+	panes := slices.Clone(window.Panes)
+	slices.SortFunc(panes, func(a, b tmux.Pane) int { return a.Index - b.Index })
+	paneIds := make([]string, len(panes))
+	for i, pane := range panes {
+		paneIds[i] = pane.Id
+	}
+
+	paneDirs, err := paneDirectories(w, layoutWindow, session.Path)
+	if err != nil {
+		return err
+	}
+	keys, processes := launchCommands(windowCommands(layout, w))
+	for i := range paneIds {
+		keys[i] = nil
+	}
+
+	paneIds, err = splitPanes(server, w, paneIds, layoutWindow, paneDirs, processes)
+	if err != nil {
+		return err
+	}
+	if layoutWindow.Layout != "" {
+		if err := server.SelectLayout(window.Id, string(layoutWindow.Layout)); err != nil {
+			return &LayoutError{Window: w, Pane: -1, Err: fmt.Errorf("select layout: %w", err)}
+		}
+	}
+	return typeCommands(server, w, paneIds, keys)
+}
+
+// ReconcileLayout adds the windows and panes of the layout a running session
+// lacks, so that layout changes reach sessions started before them. Windows
+// are matched by name, unnamed ones by position. Existing panes, their
+// commands and windows the layout does not know are left alone. It returns a
+// description of every change.
+func ReconcileLayout(server *tmux.Server, session tmux.Session, layout tmuxp.Layout) ([]string, error) {
+	windows := slices.Clone(session.Windows)
+	slices.SortFunc(windows, func(a, b tmux.Window) int { return a.Index - b.Index })
+
+	changes := []string{}
+	for i, j := range matchWindows(windows, layout.Windows) {
+		if j < 0 {
+			windowId, paneId, err := addWindow(server, session, layout.Windows[i], i)
+			if err != nil {
+				return changes, err
+			}
+			if err := configureWindow(server, session, layout, i, windowId, paneId); err != nil {
+				return changes, err
+			}
+			changes = append(changes, fmt.Sprintf("%s: added", windowLabel(layout, i)))
+			continue
+		}
+
+		missing := len(layout.Windows[i].Panes) - len(windows[j].Panes)
+		if missing <= 0 {
+			continue
+		}
+		if err := addPanes(server, session, layout, i, windows[j]); err != nil {
+			return changes, err
+		}
+		if missing == 1 {
+			changes = append(changes, fmt.Sprintf("%s: added 1 pane", windowLabel(layout, i)))
+		} else {
+			changes = append(changes, fmt.Sprintf("%s: added %d panes", windowLabel(layout, i), missing))
+		}
+	}
+	return changes, nil
+}
+
+// RebuildSession replaces a running session by a new one set up by the
+// layout. The old session is kept under another name until the new one is
+// ready, and gets its name back if the layout fails. Its clients are moved to
+// the new session before it is killed.
+func RebuildSession(server *tmux.Server, session tmux.Session, layout tmuxp.Layout) (tmux.Session, error) {
+	oldName := session.Name + "-old"
+	if err := server.RenameSession(session.Id, oldName); err != nil {
+		return tmux.Session{}, err
+	}
+	restore := func() {
+		if err := server.RenameSession(session.Id, session.Name); err != nil {
+			util.DebugLog("layout: restore session name: %v", err)
+		}
+	}
+
+	fresh, err := server.AddSession(session.Name, session.Path, layout.Env()...)
+	if err != nil {
+		restore()
+		return tmux.Session{}, err
+	}
+	if err := ApplyLayout(server, fresh, layout); err != nil {
+		if killErr := server.KillSession(fresh.Name); killErr != nil {
+			util.DebugLog("layout: kill partial session: %v", killErr)
+		}
+		restore()
+		return tmux.Session{}, err
+	}
+
+	clients, err := server.ListClients(session.Id)
+	if err != nil {
+		return fresh, err
+	}
+	for _, client := range clients {
+		if err := server.SwitchClient(client, fresh.Id); err != nil {
+			return fresh, err
+		}
+	}
+	// this process may run inside the old session, so this comes last
+	return fresh, server.KillSession(oldName)
+}
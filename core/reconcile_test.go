@@ -0,0 +1,115 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/oschrenk/sessionizer/internal/shell"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+func TestMatchWindows(t *testing.T) {
+	running := []tmux.Window{{Name: "zsh"}, {Name: "edit"}, {Name: "logs"}}
+	layoutWindows := []tmuxp.Window{{Name: "logs"}, {}, {Name: "tests"}, {}}
+
+	// logs by name, the unnamed second window by position, tests and the
+	// fourth window are missing
+	if got, want := matchWindows(running, layoutWindows), []int{2, 1, -1, -1}; !slices.Equal(got, want) {
+		t.Errorf("matchWindows() = %v, want %v", got, want)
+	}
+}
+
+func TestSessionLayoutPath(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "layouts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "layouts", "work.yml"), []byte("windows: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	entries := []model.Entry{
+		{Label: "other", Path: "/elsewhere"},
+		{Label: "My.App", Path: dir, Layout: "work"},
+	}
+	want := filepath.Join(configDir, "layouts", "work.yml")
+
+	if got := SessionLayoutPath(tmux.Session{Name: "my-app", Path: "/moved"}, entries, configDir); got != want {
+		t.Errorf("SessionLayoutPath() by name = %q, want %q", got, want)
+	}
+	if got := SessionLayoutPath(tmux.Session{Name: "renamed", Path: dir}, entries, configDir); got != want {
+		t.Errorf("SessionLayoutPath() by path = %q, want %q", got, want)
+	}
+	if got := SessionLayoutPath(tmux.Session{Name: "unknown", Path: "/unknown"}, entries, configDir); got != "" {
+		t.Errorf("SessionLayoutPath() without entry = %q, want none", got)
+	}
+}
+
+func TestReadSessionLayout(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "layouts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := "template: true\nwindows:\n  - window_name: '{{ .Name }}'\n    panes:\n      - go test {{ .Vars.packages }}\n"
+	if err := os.WriteFile(filepath.Join(configDir, "layouts", "go.yml"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "app")
+	entries := []model.Entry{
+		{Label: "App", Path: dir, Layout: "go", Vars: map[string]string{"packages": "./cmd/..."}},
+	}
+
+	layout, layoutPath, err := ReadSessionLayout(tmux.Session{Name: "app", Path: dir}, entries, configDir)
+	if err != nil {
+		t.Fatalf("ReadSessionLayout() error: %v", err)
+	}
+	if want := filepath.Join(configDir, "layouts", "go.yml"); layoutPath != want {
+		t.Errorf("ReadSessionLayout() path = %q, want %q", layoutPath, want)
+	}
+	window := layout.Windows[0]
+	if window.Name != "app" || len(window.Panes[0].ShellCommand) != 1 || window.Panes[0].ShellCommand[0] != "go test ./cmd/..." {
+		t.Errorf("ReadSessionLayout() window = %+v, want app running go test ./cmd/...", window)
+	}
+
+	layout, _, err = ReadSessionLayout(tmux.Session{Name: "unknown", Path: "/unknown"}, entries, configDir)
+	if err != nil || layout != nil {
+		t.Errorf("ReadSessionLayout() without entry = %v, %v, want none", layout, err)
+	}
+}
+
+func TestReconcileLayout(t *testing.T) {
+	dir := t.TempDir()
+	session := tmux.Session{Id: "$1", Name: "app", Path: dir, Windows: []tmux.Window{
+		{Id: "@1", Name: "edit", Panes: []tmux.Pane{{Id: "%1"}}},
+		{Id: "@2", Index: 1, Name: "logs", Panes: []tmux.Pane{{Id: "%2"}, {Id: "%3", Index: 1}}},
+	}}
+	layout := tmuxp.Layout{Windows: []tmuxp.Window{
+		{Name: "edit", Panes: []tmuxp.Pane{{ShellCommand: []string{"nvim"}}, {ShellCommand: []string{"make test"}}}},
+		{Name: "logs", Panes: []tmuxp.Pane{{}}},
+		{Name: "server", Panes: []tmuxp.Pane{{ShellCommand: []string{"make run"}}}},
+	}}
+
+	plan := new(tmux.Plan)
+	changes, err := ReconcileLayout(tmux.DryRun(plan), session, layout)
+	if err != nil {
+		t.Fatalf("ReconcileLayout() error: %v", err)
+	}
+	if want := []string{"window 0 (edit): added 1 pane", "window 2 (server): added"}; !slices.Equal(changes, want) {
+		t.Errorf("ReconcileLayout() = %q, want %q", changes, want)
+	}
+
+	// nvim runs already, only the new panes get their commands
+	keys := []string{}
+	for _, command := range plan.Commands {
+		if command[1] == "send-keys" {
+			keys = append(keys, command[4])
+		}
+	}
+	if want := []string{"make test", "cd " + shell.Quote(dir), "make run"}; !slices.Equal(keys, want) {
+		t.Errorf("ReconcileLayout() sent %q, want %q", keys, want)
+	}
+}
@@ -1,60 +1,32 @@
 package core
 
 import (
-	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
 
+	"github.com/oschrenk/sessionizer/internal/git"
 	"github.com/oschrenk/sessionizer/internal/tmux"
 	"github.com/oschrenk/sessionizer/internal/tmuxp"
+	"github.com/oschrenk/sessionizer/internal/util"
 	"github.com/oschrenk/sessionizer/model"
 )
 
-const layoutFileName = ".sessionizer.yml"
+// LayoutFileName is the name of the layout file looked up in a session directory.
+const LayoutFileName = ".sessionizer.yml"
 
 // EntriesFromDir finds all project directories within a given directory
-// that match the rooter patterns, ignoring specified directories
-func EntriesFromDir(dir string, ignore []string, rooterPatterns []string) ([]model.Entry, error) {
-	projects := []model.Entry{}
-
-	filepath.WalkDir(dir, func(path string, file fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if file.IsDir() {
-			// check if any rooter pattern exists in this directory
-			hasRooterPattern := false
-			for _, pattern := range rooterPatterns {
-				if _, err := os.Stat(filepath.Join(path, pattern)); err == nil {
-					hasRooterPattern = true
-					break
-				}
-			}
-
-			if !hasRooterPattern {
-				// ignore directories
-				if slices.Contains(ignore, file.Name()) {
-					return filepath.SkipDir
-				}
-				// no rooter pattern found, continue search
-				return nil
-			} else {
-				label := strings.ReplaceAll(path, dir+"/", "")
-				projects = append(projects, model.Entry{Label: label, Path: path})
-
-				// don't extends search breadth
-				// that stops from build directories or sub-Projects
-				// from being picked up
-				return filepath.SkipDir
-			}
-		}
-
-		return nil
-	})
-
-	return projects, nil
+// that match the rooter patterns, ignoring specified directories.
+// Subdirectories are scanned by up to workers goroutines, see walkDirs.
+func EntriesFromDir(dir string, ignore []string, rooterPatterns []string, workers int) ([]model.Entry, error) {
+	config := model.Config{
+		SearchDirs:     []model.SearchDir{{Path: dir}},
+		Ignore:         ignore,
+		RooterPatterns: rooterPatterns,
+		Workers:        workers,
+	}
+	return walkDirs(config, nil, nil)[0], nil
 }
 
 // EntryFromSearchEntry creates an entry from a SearchEntry.
@@ -65,11 +37,24 @@ func EntryFromSearchEntry(se model.SearchEntry) model.Entry {
 	if label == "" {
 		label = filepath.Base(se.Path)
 	}
-	return model.Entry{Label: label, Path: se.Path, Layout: se.Layout}
+	return model.Entry{Label: label, Path: se.Path, Layout: se.Layout, Vars: se.Vars}
 }
 
 // BuildEntries creates a list of all searchable entries based on configuration
 func BuildEntries(config model.Config) ([]model.Entry, error) {
+	discovered := []model.Entry{}
+
+	// search through all directories at once
+	for _, dirProjects := range walkDirs(config, nil, nil) {
+		discovered = append(discovered, dirProjects...)
+	}
+
+	return assembleEntries(config, discovered), nil
+}
+
+// assembleEntries combines the default entry, the projects discovered in the
+// search directories and the specific search entries, in that order.
+func assembleEntries(config model.Config, discovered []model.Entry) []model.Entry {
 	allProjects := []model.Entry{}
 	// TODO this should not allow a session name with `.` or `:`
 	// only offer the default entry when a default name is configured
@@ -81,28 +66,21 @@ func BuildEntries(config model.Config) ([]model.Entry, error) {
 		})
 	}
 
-	// search through directories
-	for _, searchDir := range config.SearchDirs {
-		dirProjects, err := EntriesFromDir(searchDir, config.Ignore, config.RooterPatterns)
-		if err != nil {
-			return nil, err
-		}
-		allProjects = append(allProjects, dirProjects...)
-	}
+	allProjects = append(allProjects, discovered...)
 
 	// add specific entries
 	for _, entryPath := range config.SearchEntries {
 		allProjects = append(allProjects, EntryFromSearchEntry(entryPath))
 	}
 
-	return allProjects, nil
+	return allProjects
 }
 
 // resolveLayoutPath returns the path to the layout file to apply, or "" if none.
 // Precedence: local .sessionizer.yml > direct layoutPath > named layout from
 // configDir/layouts/ > none.
 func resolveLayoutPath(sessionPath string, layout string, layoutPath string, configDir string) string {
-	localPath := filepath.Join(sessionPath, layoutFileName)
+	localPath := filepath.Join(sessionPath, LayoutFileName)
 	if _, err := os.Stat(localPath); err == nil {
 		return localPath
 	}
@@ -121,22 +99,119 @@ func resolveLayoutPath(sessionPath string, layout string, layoutPath string, con
 	return ""
 }
 
+// LayoutFiles returns every layout file in use: the named layouts in
+// configDir/layouts/, and the local .sessionizer.yml and layout_path of every
+// entry, each once.
+func LayoutFiles(entries []model.Entry, configDir string) []string {
+	files, _ := filepath.Glob(filepath.Join(configDir, "layouts", "*.yml"))
+	for _, entry := range entries {
+		if entry.Session != "" {
+			continue
+		}
+		candidates := []string{filepath.Join(entry.Path, LayoutFileName)}
+		if entry.LayoutPath != "" {
+			candidates = append(candidates, tmuxp.ExpandPath(entry.LayoutPath))
+		}
+		for _, candidate := range candidates {
+			if _, err := os.Stat(candidate); err == nil && !slices.Contains(files, candidate) {
+				files = append(files, candidate)
+			}
+		}
+	}
+	return files
+}
+
+// StartOptions change how StartSession creates a session.
+type StartOptions struct {
+	// KeepPartial keeps a session whose layout failed to apply, for
+	// debugging the layout, instead of killing it
+	KeepPartial bool
+	// Plan, if set, makes a dry run: the tmux commands are recorded in it
+	// instead of being run, as if the session did not exist yet
+	Plan *tmux.Plan
+	// Vars are the variables of the entry for its layout template
+	Vars map[string]string
+}
+
+// templateData returns the variables a layout of the entry with the given
+// label and path can use.
+func templateData(label string, path string, vars map[string]string) tmuxp.TemplateData {
+	// outside of a repository there is no branch
+	branch, _ := git.Branch(path)
+	return tmuxp.TemplateData{
+		Name:    filepath.Base(path),
+		Label:   label,
+		Path:    path,
+		Branch:  branch,
+		Session: tmux.NormalizeName(label),
+		Vars:    vars,
+	}
+}
+
+// readLayout reads the layout at layoutPath for the entry with the given
+// label and path. A template that sets session_name is read a second time, so
+// that {{ .Session }} is the session the layout names.
+func readLayout(layoutPath string, label string, path string, vars map[string]string) (*tmuxp.Layout, error) {
+	data := templateData(label, path, vars)
+	l, err := tmuxp.ReadLayoutTemplate(layoutPath, data)
+	if err != nil || !l.Template || l.SessionName == "" || tmux.NormalizeName(l.SessionName) == data.Session {
+		return l, err
+	}
+	// session_name itself keeps the session named after the label
+	sessionName := l.SessionName
+	data.Session = tmux.NormalizeName(sessionName)
+	if l, err = tmuxp.ReadLayoutTemplate(layoutPath, data); err != nil {
+		return nil, err
+	}
+	l.SessionName = sessionName
+	return l, nil
+}
+
 // StartSession creates or attaches to a tmux session with the given name and path.
 // If no local .sessionizer.yml exists, it resolves a layout from the direct
 // layoutPath, or from a named layout file at configDir/layouts/<layout>.yml.
-func StartSession(name string, path string, layout string, layoutPath string, configDir string) error {
+// A layout that sets template is executed with the variables of the entry
+// first, a layout that sets session_name names the session instead of name.
+// Creating a session is all or nothing: if its layout fails, the session is
+// killed again, unless options keep it.
+// Every open is recorded in the history used to rank entries, except for dry
+// runs.
+func StartSession(name string, path string, layout string, layoutPath string, configDir string, options StartOptions) error {
 	server := new(tmux.Server)
+	if options.Plan != nil {
+		server = tmux.DryRun(options.Plan)
+	}
 
 	var session tmux.Session
 	var freshlyCreated bool
 
-	sessionPtr, err := server.SessionByName(name)
+	// the layout may name the session and set its environment, so it is
+	// read before looking for the session. A broken layout only matters
+	// when the session has to be created.
+	var l *tmuxp.Layout
+	var layoutErr error
+	sessionName := name
+	if resolvedPath := resolveLayoutPath(path, layout, layoutPath, configDir); resolvedPath != "" {
+		l, layoutErr = readLayout(resolvedPath, name, path, options.Vars)
+		if layoutErr == nil && l.SessionName != "" {
+			sessionName = l.SessionName
+		}
+	}
+
+	sessionPtr, err := server.SessionByName(sessionName)
 	if err != nil {
 		return err
 	}
 
 	if sessionPtr == nil {
-		session, err = server.AddSession(name, path)
+		if layoutErr != nil {
+			return layoutErr
+		}
+		var environment []string
+		if l != nil {
+			environment = l.Env()
+		}
+		session, err = server.AddSession(sessionName, path, environment...)
 		if err != nil {
 			return err
 		}
@@ -146,16 +221,24 @@ func StartSession(name string, path string, layout string, layoutPath string, co
 		freshlyCreated = false
 	}
 
-	if freshlyCreated {
-		if resolvedPath := resolveLayoutPath(path, layout, layoutPath, configDir); resolvedPath != "" {
-			l, err := tmuxp.ReadLayoutFromFile(resolvedPath)
-			if err != nil {
-				return err
-			}
-			err = ApplyLayout(server, session, *l)
-			if err != nil {
-				return err
+	if freshlyCreated && l != nil {
+		err = ApplyLayout(server, session, *l)
+		if err != nil {
+			// a half built session would be attached to as is next time,
+			// without applying the layout again
+			if !options.KeepPartial && options.Plan == nil {
+				if killErr := server.KillSession(session.Name); killErr != nil {
+					util.DebugLog("layout: kill partial session: %v", killErr)
+				}
 			}
+			return err
+		}
+	}
+
+	// the history only ranks entries, failing to update it is not fatal
+	if options.Plan == nil {
+		if err := recordOpen(name, path); err != nil {
+			util.DebugLog("history: %v", err)
 		}
 	}
 
@@ -3,13 +3,18 @@ package core
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/model"
 )
 
 func TestResolveLayoutPath(t *testing.T) {
 	// sessionDir holds an optional local .sessionizer.yml
 	sessionDir := t.TempDir()
-	localLayout := filepath.Join(sessionDir, layoutFileName)
+	localLayout := filepath.Join(sessionDir, LayoutFileName)
 
 	// configDir holds named layouts under layouts/
 	configDir := t.TempDir()
@@ -93,3 +98,86 @@ func TestResolveLayoutPath(t *testing.T) {
 		})
 	}
 }
+
+func TestStartSessionDryRun(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	layout := "session_name: planned\nbefore_script: ./setup.sh\nwindows:\n  - panes: [echo one, echo two]\n"
+	if err := os.WriteFile(filepath.Join(dir, LayoutFileName), []byte(layout), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// setup.sh does not exist, running it would fail
+	plan := new(tmux.Plan)
+	if err := StartSession("app", dir, "", "", "", StartOptions{Plan: plan}); err != nil {
+		t.Fatalf("StartSession() error: %v", err)
+	}
+
+	commands := []string{}
+	for _, command := range plan.Commands {
+		if command[0] == "tmux" {
+			commands = append(commands, command[1])
+		} else {
+			commands = append(commands, command[0])
+		}
+	}
+	want := []string{"new-session", "./setup.sh", "split-window", "send-keys", "send-keys", "send-keys", "select-window", "attach-session"}
+	if !slices.Equal(commands, want) {
+		t.Errorf("plan = %q, want %q", commands, want)
+	}
+	if history, _ := HistoryPath(); history != "" {
+		if _, err := os.Stat(history); !os.IsNotExist(err) {
+			t.Errorf("dry run recorded the open in the history")
+		}
+	}
+}
+
+func TestLayoutFiles(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "layouts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	named := filepath.Join(configDir, "layouts", "work.yml")
+	project := t.TempDir()
+	local := filepath.Join(project, LayoutFileName)
+	direct := filepath.Join(t.TempDir(), "direct.yml")
+	for _, file := range []string{named, local, direct} {
+		if err := os.WriteFile(file, []byte("windows: []\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries := []model.Entry{
+		{Label: "project", Path: project},
+		{Label: "same project", Path: project, LayoutPath: direct},
+		{Label: "no layout", Path: t.TempDir(), LayoutPath: "/missing.yml"},
+	}
+	if got, want := LayoutFiles(entries, configDir), []string{named, local, direct}; !slices.Equal(got, want) {
+		t.Errorf("LayoutFiles() = %q, want %q", got, want)
+	}
+}
+
+func TestStartSessionTemplateSessionName(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "app")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	layout := "template: true\nsession_name: '{{ .Session }}.Dev'\nwindows:\n  - panes: ['echo {{ .Session }}']\n"
+	if err := os.WriteFile(filepath.Join(dir, LayoutFileName), []byte(layout), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// session_name is executed with the session named after the label, the
+	// rest of the layout with the session it names
+	plan := new(tmux.Plan)
+	if err := StartSession("Org/App", dir, "", "", "", StartOptions{Plan: plan}); err != nil {
+		t.Fatalf("StartSession() error: %v", err)
+	}
+	got := plan.String()
+	for _, want := range []string{" -s org/app-dev ", "'echo org/app-dev'"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("plan = %s, missing %q", got, want)
+		}
+	}
+}
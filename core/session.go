@@ -0,0 +1,101 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+// SessionState describes the tmux session of an entry.
+type SessionState int
+
+const (
+	// NoSession means the entry has no running session.
+	NoSession SessionState = iota
+	// DetachedSession means the session runs without any client.
+	DetachedSession
+	// AttachedSession means at least one client shows the session.
+	AttachedSession
+	// CurrentSession means the session is the one sessionizer runs in.
+	CurrentSession
+)
+
+// SessionName returns the name of the tmux session an entry opens.
+func SessionName(entry model.Entry) string {
+	if entry.Session != "" {
+		return entry.Session
+	}
+	return tmux.NormalizeName(entry.Label)
+}
+
+// SessionFor returns the session that an entry opens, or nil if it is not running.
+func SessionFor(entry model.Entry, sessions []tmux.Session) *tmux.Session {
+	name := SessionName(entry)
+	for i := range sessions {
+		if sessions[i].Name == name {
+			return &sessions[i]
+		}
+	}
+	return nil
+}
+
+// SessionStateOf returns the state of the session of entry, where current is
+// the name of the session sessionizer runs in, if any.
+func SessionStateOf(entry model.Entry, sessions []tmux.Session, current string) SessionState {
+	session := SessionFor(entry, sessions)
+	if session == nil {
+		return NoSession
+	}
+	return StateOfSession(*session, current)
+}
+
+// StateOfSession returns the state of a running session, where current is the
+// name of the session sessionizer runs in, if any.
+func StateOfSession(session tmux.Session, current string) SessionState {
+	switch {
+	case session.Name == current:
+		return CurrentSession
+	case session.Attached:
+		return AttachedSession
+	}
+	return DetachedSession
+}
+
+// SessionEntries returns an entry for every running session that none of
+// entries opens, so that sessions created outside sessionizer can be found too.
+func SessionEntries(entries []model.Entry, sessions []tmux.Session) []model.Entry {
+	known := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		known[SessionName(entry)] = true
+	}
+
+	extra := []model.Entry{}
+	for _, session := range sessions {
+		if !known[session.Name] {
+			extra = append(extra, model.Entry{Label: session.Name, Path: session.Path, Session: session.Name})
+		}
+	}
+	return extra
+}
+
+// AddEntry adds a project found after SessionEntries was built and returns
+// the result. It takes the place of the entry of the running session it opens,
+// otherwise it is inserted at its rank, see InsertRanked.
+func AddEntry(entries []model.Entry, entry model.Entry, scores map[string]float64) []model.Entry {
+	i := slices.IndexFunc(entries, func(e model.Entry) bool {
+		return e.Session != "" && e.Session == SessionName(entry)
+	})
+	if i < 0 || entry.Session != "" {
+		return InsertRanked(entries, entry, scores)
+	}
+	entries[i] = entry
+	return entries
+}
+
+// AttachSession switches to, or attaches, the running session with exactly
+// this name.
+func AttachSession(name string) error {
+	server := new(tmux.Server)
+	return server.AttachSession(tmux.Session{Name: name})
+}
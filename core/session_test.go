@@ -0,0 +1,79 @@
+package core
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+func TestSessionStateOf(t *testing.T) {
+	sessions := []tmux.Session{
+		{Name: "org/app", Attached: true},
+		{Name: "notes"},
+		{Name: "main"},
+	}
+
+	tests := []struct {
+		entry model.Entry
+		want  SessionState
+	}{
+		{entry: model.Entry{Label: "Org/App"}, want: AttachedSession},
+		{entry: model.Entry{Label: "notes"}, want: DetachedSession},
+		{entry: model.Entry{Label: "Main", Session: "main"}, want: CurrentSession},
+		{entry: model.Entry{Label: "other"}, want: NoSession},
+	}
+	for _, tt := range tests {
+		if got := SessionStateOf(tt.entry, sessions, "main"); got != tt.want {
+			t.Errorf("SessionStateOf(%q) = %v, want %v", tt.entry.Label, got, tt.want)
+		}
+	}
+}
+
+func TestSessionEntries(t *testing.T) {
+	entries := []model.Entry{{Label: "Org/App"}, {Label: "notes"}}
+	sessions := []tmux.Session{
+		{Name: "org/app", Path: "/p/app"},
+		{Name: "scratch", Path: "/tmp"},
+	}
+
+	got := SessionEntries(entries, sessions)
+	want := model.Entry{Label: "scratch", Path: "/tmp", Session: "scratch"}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("SessionEntries() = %v, want [%v]", got, want)
+	}
+}
+
+func TestAddEntry(t *testing.T) {
+	sessions := []tmux.Session{
+		{Name: "org/app", Path: "/p/app"},
+		{Name: "scratch", Path: "/tmp"},
+		{Name: "api", Path: "/p/api"},
+	}
+	entries := []model.Entry{{Label: "notes", Path: "/p/notes"}}
+	entries = append(entries, SessionEntries(entries, sessions)...)
+
+	// projects streaming in after the session entries were built replace the
+	// entry of the session they open, a session merely running in the
+	// directory of a project is kept
+	for _, project := range []model.Entry{
+		{Label: "Org/App", Path: "/p/app"},
+		{Label: "backend", Path: "/p/api"},
+		{Label: "web", Path: "/p/web"},
+	} {
+		entries = AddEntry(entries, project, nil)
+	}
+
+	labels := []string{}
+	for _, entry := range entries {
+		labels = append(labels, entry.Label)
+	}
+	if want := []string{"notes", "Org/App", "scratch", "api", "backend", "web"}; !slices.Equal(labels, want) {
+		t.Errorf("AddEntry() = %v, want %v", labels, want)
+	}
+	if entries[1].Session != "" {
+		t.Errorf("AddEntry() kept the session of %q, want the project", entries[1].Label)
+	}
+}
@@ -0,0 +1,305 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oschrenk/sessionizer/internal/shell"
+	"github.com/oschrenk/sessionizer/internal/tmux"
+	"github.com/oschrenk/sessionizer/internal/tmuxp"
+	"github.com/oschrenk/sessionizer/internal/xdg"
+)
+
+const snapshotVersion = 1
+
+const snapshotFileName = "snapshot.json"
+
+// shells are the commands of panes that are idle at a prompt, they are not
+// started again on restore
+var shells = []string{"bash", "zsh", "fish", "sh", "dash", "ksh", "tcsh", "csh", "nu"}
+
+// Snapshot holds the sessions of a tmux server, so that they can be recreated
+// after the server is gone.
+type Snapshot struct {
+	Version  int               `json:"version"`
+	SavedAt  time.Time         `json:"saved_at"`
+	Sessions []SessionSnapshot `json:"sessions"`
+}
+
+// SessionSnapshot describes a session and its windows, in index order.
+type SessionSnapshot struct {
+	Name    string           `json:"name"`
+	Path    string           `json:"path"`
+	Windows []WindowSnapshot `json:"windows"`
+}
+
+// WindowSnapshot describes a window and its panes, in index order.
+type WindowSnapshot struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+	// Layout is the tmux layout string with the size and position of
+	// every pane
+	Layout string         `json:"layout"`
+	Panes  []PaneSnapshot `json:"panes"`
+}
+
+// PaneSnapshot describes a pane. Command is empty for a pane idle at a shell
+// prompt.
+type PaneSnapshot struct {
+	Path    string `json:"path"`
+	Command string `json:"command,omitempty"`
+	Active  bool   `json:"active"`
+}
+
+// SnapshotPath returns the location of the snapshot in the XDG state directory.
+func SnapshotPath() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, snapshotFileName), nil
+}
+
+// LoadSnapshot reads a snapshot from path.
+func LoadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, err
+	}
+	if snapshot.Version != snapshotVersion {
+		return Snapshot{}, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to path.
+func (s Snapshot) Save(path string) error {
+	s.Version = snapshotVersion
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// TakeSnapshot captures every session of the server.
+func TakeSnapshot(server *tmux.Server) (Snapshot, error) {
+	sessions, err := server.ListSessions(false)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot := Snapshot{Version: snapshotVersion, SavedAt: time.Now()}
+	for _, session := range sessions {
+		snapshot.Sessions = append(snapshot.Sessions, snapshotSession(session, foregroundCommand))
+	}
+	return snapshot, nil
+}
+
+// snapshotSession converts a running session, using command to look up the
+// command line running in a pane.
+func snapshotSession(session tmux.Session, command func(tmux.Pane) string) SessionSnapshot {
+	windows := slices.Clone(session.Windows)
+	slices.SortFunc(windows, func(a, b tmux.Window) int { return a.Index - b.Index })
+
+	result := SessionSnapshot{Name: session.Name, Path: session.Path}
+	for _, window := range windows {
+		panes := slices.Clone(window.Panes)
+		slices.SortFunc(panes, func(a, b tmux.Pane) int { return a.Index - b.Index })
+
+		w := WindowSnapshot{Name: window.Name, Active: window.Active, Layout: window.Layout}
+		for _, pane := range panes {
+			p := PaneSnapshot{Path: pane.Path, Active: pane.Active}
+			if !isShell(pane.Command) {
+				p.Command = command(pane)
+			}
+			w.Panes = append(w.Panes, p)
+		}
+		result.Windows = append(result.Windows, w)
+	}
+	return result
+}
+
+// foregroundCommand returns the command line of the process in the foreground
+// of the pane, tmux itself only knows its name. It falls back to the name if
+// the process cannot be looked up.
+func foregroundCommand(pane tmux.Pane) string {
+	// the foreground process group of the terminal of the pane's shell
+	out, _, err := shell.Run("ps", []string{"-o", "tpgid=", "-p", strconv.Itoa(pane.Pid)})
+	if err != nil {
+		return pane.Command
+	}
+	pgid := strings.TrimSpace(out)
+	out, _, err = shell.Run("ps", []string{"-o", "args=", "-p", pgid})
+	if err != nil || strings.TrimSpace(out) == "" {
+		return pane.Command
+	}
+	return strings.TrimSpace(out)
+}
+
+// isShell reports whether command is an interactive shell, either a common
+// one or the login shell of the user.
+func isShell(command string) bool {
+	if command == "" || slices.Contains(shells, command) {
+		return true
+	}
+	shell := os.Getenv("SHELL")
+	return shell != "" && filepath.Base(shell) == command
+}
+
+// RestoreSnapshot recreates the sessions of the snapshot. Sessions that
+// already exist are left untouched and returned as skipped. Unless commands is
+// false, the command that ran in a pane is started again.
+func RestoreSnapshot(server *tmux.Server, snapshot Snapshot, commands bool) (restored []string, skipped []string, err error) {
+	// without a server there is nothing to skip
+	running, _ := server.ListSessions(false)
+	exists := func(name string) bool {
+		return slices.ContainsFunc(running, func(s tmux.Session) bool {
+			return s.Name == name || s.Name == tmux.NormalizeName(name)
+		})
+	}
+
+	for _, session := range snapshot.Sessions {
+		if exists(session.Name) {
+			skipped = append(skipped, session.Name)
+			continue
+		}
+		if err := restoreSession(server, session, commands); err != nil {
+			return restored, skipped, fmt.Errorf("restore session %s: %w", session.Name, err)
+		}
+		restored = append(restored, session.Name)
+	}
+	return restored, skipped, nil
+}
+
+// restoreSession creates a session with its windows and panes.
+func restoreSession(server *tmux.Server, snapshot SessionSnapshot, commands bool) error {
+	session, err := server.AddSession(snapshot.Name, snapshot.Path)
+	if err != nil {
+		return err
+	}
+
+	var activeWindowId string
+	first := true
+	for i, window := range snapshot.Windows {
+		if len(window.Panes) == 0 {
+			continue
+		}
+
+		var windowId string
+		var paneId string
+		// cd is the directory to change to in the first pane, if it was
+		// not created there
+		var cd string
+		if first {
+			// the session comes with its first window
+			windowId = session.Windows[0].Id
+			paneId = session.Windows[0].Panes[0].Id
+			if err := server.RenameWindow(windowId, window.Name); err != nil {
+				return fmt.Errorf("rename window: %w", err)
+			}
+			if dir := window.Panes[0].Path; dir != "" && dir != snapshot.Path {
+				cd = dir
+			}
+		} else {
+			windowId, err = server.AddWindow(session.Id+":", window.Name, window.Panes[0].Path)
+			if err != nil {
+				return fmt.Errorf("create window %d: %w", i, err)
+			}
+			panes, err := server.ListPanes(windowId)
+			if err != nil || len(panes) == 0 {
+				return fmt.Errorf("could not find pane for new window %d", i)
+			}
+			paneId = panes[0].Id
+		}
+		first = false
+
+		if err := restoreWindow(server, windowId, paneId, cd, window, commands); err != nil {
+			return fmt.Errorf("restore window %d: %w", i, err)
+		}
+		if window.Active {
+			activeWindowId = windowId
+		}
+	}
+
+	if activeWindowId != "" {
+		if err := server.SelectWindow(activeWindowId); err != nil {
+			return fmt.Errorf("select window: %w", err)
+		}
+	}
+	return nil
+}
+
+// restoreWindow splits the window into the panes of the snapshot, with their
+// directories, and arranges them by the saved layout string. The first pane
+// changes to cd, if set.
+func restoreWindow(server *tmux.Server, windowId string, firstPaneId string, cd string, snapshot WindowSnapshot, commands bool) error {
+	paneIds := []string{firstPaneId}
+	for i := 1; i < len(snapshot.Panes); i++ {
+		paneId, err := server.SplitPane(paneIds[i-1], tmux.SplitOptions{Direction: tmux.Vertical, StartDirectory: snapshot.Panes[i].Path})
+		if err != nil {
+			return fmt.Errorf("split pane %d: %w", i, err)
+		}
+		paneIds = append(paneIds, paneId)
+
+		// make room for the next split, the saved layout is applied at the end
+		if err := server.SelectLayout(windowId, string(tmuxp.Tiled)); err != nil {
+			return fmt.Errorf("select layout: %w", err)
+		}
+	}
+
+	if snapshot.Layout != "" {
+		if err := server.SelectLayout(windowId, snapshot.Layout); err != nil {
+			return fmt.Errorf("select layout: %w", err)
+		}
+	}
+
+	keys := make([][]string, len(snapshot.Panes))
+	if cd != "" {
+		keys[0] = append(keys[0], "cd "+shell.Quote(cd))
+	}
+	var activePaneId string
+	for i, pane := range snapshot.Panes {
+		if commands && pane.Command != "" {
+			keys[i] = append(keys[i], pane.Command)
+		}
+		if pane.Active {
+			activePaneId = paneIds[i]
+		}
+	}
+
+	// like layouts, wait for the shells before typing into them
+	waiting := []string{}
+	for i := range keys {
+		if len(keys[i]) > 0 {
+			waiting = append(waiting, paneIds[i])
+		}
+	}
+	waitForShells(server, waiting)
+
+	for i := range keys {
+		for _, key := range keys[i] {
+			if err := server.SendKeys(paneIds[i], key); err != nil {
+				return fmt.Errorf("send keys to pane %d: %w", i, err)
+			}
+		}
+	}
+
+	if activePaneId != "" {
+		if err := server.SelectPane(activePaneId); err != nil {
+			return fmt.Errorf("select pane: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/oschrenk/sessionizer/internal/tmux"
+)
+
+func TestSnapshotSession(t *testing.T) {
+	t.Setenv("SHELL", "/usr/local/bin/elvish")
+
+	session := tmux.Session{
+		Name: "org/app",
+		Path: "/p/app",
+		Windows: []tmux.Window{
+			{Index: 2, Name: "logs", Layout: "aca0,80x24,0,0,3", Panes: []tmux.Pane{
+				{Index: 0, Command: "elvish", Path: "/var/log"},
+			}},
+			{Index: 1, Name: "edit", Active: true, Layout: "b25d,80x24,0,0{40x24,0,0,1,39x24,41,0,2}", Panes: []tmux.Pane{
+				{Index: 1, Command: "zsh", Path: "/p/app/src", Active: true},
+				{Index: 0, Command: "nvim", Path: "/p/app"},
+			}},
+		},
+	}
+	command := func(pane tmux.Pane) string { return pane.Command + " main.go" }
+
+	want := SessionSnapshot{
+		Name: "org/app",
+		Path: "/p/app",
+		Windows: []WindowSnapshot{
+			{Name: "edit", Active: true, Layout: "b25d,80x24,0,0{40x24,0,0,1,39x24,41,0,2}", Panes: []PaneSnapshot{
+				{Path: "/p/app", Command: "nvim main.go"},
+				{Path: "/p/app/src", Active: true},
+			}},
+			{Name: "logs", Layout: "aca0,80x24,0,0,3", Panes: []PaneSnapshot{
+				{Path: "/var/log"},
+			}},
+		},
+	}
+	if got := snapshotSession(session, command); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshotSession() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSnapshotSaveLoad(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), snapshotFileName)
+	snapshot := Snapshot{Sessions: []SessionSnapshot{{Name: "notes", Path: "/p/notes"}}}
+	if err := snapshot.Save(snapshotPath); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := LoadSnapshot(snapshotPath)
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error: %v", err)
+	}
+	if len(loaded.Sessions) != 1 || loaded.Sessions[0].Name != "notes" {
+		t.Errorf("LoadSnapshot() = %+v, want the saved sessions", loaded)
+	}
+
+	if err := os.WriteFile(snapshotPath, []byte(`{"version": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(snapshotPath); err == nil {
+		t.Errorf("LoadSnapshot() accepted an unknown version")
+	}
+}
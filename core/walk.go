@@ -0,0 +1,408 @@
+package core
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/oschrenk/sessionizer/internal/git"
+	"github.com/oschrenk/sessionizer/internal/ignore"
+	"github.com/oschrenk/sessionizer/model"
+)
+
+// walkItem is a directory waiting to be visited
+type walkItem struct {
+	root *walkRoot
+	path string
+	// depth is the number of levels below the search directory
+	depth int
+	// parent is the project this directory belongs to, when looking for
+	// sub-projects by the nested rooter patterns
+	parent *model.Entry
+}
+
+// walkRoot collects the projects found below a single search directory
+type walkRoot struct {
+	model.SearchDir
+	// ignore holds the global ignore patterns, the ones of this search
+	// directory and the ones from its ignore file
+	ignore               *ignore.Matcher
+	rooterPatterns       []string
+	nestedRooterPatterns []string
+	entries              []model.Entry
+	// visited holds the resolved paths of all directories walked when
+	// following symlinks, so that links back into the tree end the walk
+	visited map[string]bool
+}
+
+// walker scans several directory trees concurrently. Directories are kept in
+// a shared queue and visited by a fixed number of workers, so wide and deep
+// trees are spread over all workers without starting a goroutine per directory.
+type walker struct {
+	// worktrees offers the linked worktrees of repositories as entries
+	worktrees bool
+	// dirs, if not nil, records the mtime of every directory descended into
+	// and of every ignore file read
+	dirs map[string]int64
+	// emit, if not nil, is called for every project as soon as it is found
+	emit func(model.Entry)
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queue holds directories not yet visited, pending counts them plus
+	// the ones currently being visited
+	queue   []walkItem
+	pending int
+	// skipped holds the linked worktrees passed over while walking, in case
+	// their repository is not found
+	skipped []skippedWorktree
+}
+
+// skippedWorktree is a linked worktree left to its repository
+type skippedWorktree struct {
+	root  *walkRoot
+	entry model.Entry
+}
+
+// walkDirs finds all projects below the search directories of config using up
+// to config.Workers concurrent directory reads; less than one means one per
+// CPU. It returns the projects of each search directory, in the configured
+// order, each sorted by path. emit, if not nil, is called for every project as
+// soon as it is found; calls are never concurrent.
+func walkDirs(config model.Config, dirs map[string]int64, emit func(model.Entry)) [][]model.Entry {
+	workers := config.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	w := &walker{
+		worktrees: config.Worktrees,
+		dirs:      dirs,
+		emit:      emit,
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	scans := make([]*walkRoot, len(config.SearchDirs))
+	for i, searchDir := range config.SearchDirs {
+		scans[i] = newWalkRoot(config, searchDir, dirs)
+		if dirs != nil {
+			// record the root even if it does not exist (yet)
+			dirs[searchDir.Path] = modTime(searchDir.Path)
+		}
+		// like filepath.WalkDir, a root that is a symlink is not followed,
+		// unless asked to
+		stat := os.Lstat
+		if searchDir.FollowSymlinks {
+			stat = os.Stat
+		}
+		if info, err := stat(searchDir.Path); err == nil && info.IsDir() {
+			w.queue = append(w.queue, walkItem{root: scans[i], path: searchDir.Path})
+		}
+	}
+	w.pending = len(w.queue)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	w.adoptWorktrees(scans)
+
+	results := make([][]model.Entry, len(scans))
+	for i, scan := range scans {
+		sortEntries(scan.entries)
+		results[i] = scan.entries
+	}
+	return results
+}
+
+// newWalkRoot combines the global settings of config with the ones of a
+// single search directory.
+func newWalkRoot(config model.Config, searchDir model.SearchDir, dirs map[string]int64) *walkRoot {
+	root := &walkRoot{
+		SearchDir:      searchDir,
+		ignore:         ignore.New(config.Ignore...),
+		rooterPatterns: config.RooterPatterns,
+		visited:        map[string]bool{},
+	}
+	root.ignore.Add(searchDir.Ignore...)
+	if len(searchDir.RooterPatterns) > 0 {
+		root.rooterPatterns = searchDir.RooterPatterns
+	}
+	root.nestedRooterPatterns = config.NestedRooterPatterns
+	if len(searchDir.NestedRooterPatterns) > 0 {
+		root.nestedRooterPatterns = searchDir.NestedRooterPatterns
+	}
+
+	if config.IgnoreFiles {
+		ignoreFile := filepath.Join(searchDir.Path, ignore.FileName)
+		if dirs != nil {
+			// editing the ignore file does not change the directory mtime
+			dirs[ignoreFile] = modTime(ignoreFile)
+		}
+		if lines, err := ignore.ReadFile(ignoreFile); err == nil {
+			root.ignore.Add(lines...)
+		}
+	}
+
+	return root
+}
+
+// work visits queued directories until the whole tree has been visited.
+func (w *walker) work() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for {
+		for len(w.queue) == 0 && w.pending > 0 {
+			w.cond.Wait()
+		}
+		if w.pending == 0 {
+			return
+		}
+
+		// take the most recently queued directory, this keeps the walk
+		// depth first and the queue short
+		item := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+
+		w.mu.Unlock()
+		children := w.visit(item)
+		w.mu.Lock()
+
+		w.queue = append(w.queue, children...)
+		w.pending += len(children) - 1
+		if w.pending == 0 || len(children) > 0 {
+			w.cond.Broadcast()
+		}
+	}
+}
+
+// visit checks a single directory. An ignored directory is skipped, one with a
+// rooter pattern becomes a project and is not descended into, otherwise its
+// subdirectories are returned.
+func (w *walker) visit(item walkItem) []walkItem {
+	root := item.root
+
+	// ignore directories, matched relative to the search directory
+	if item.depth > 0 {
+		rel, err := filepath.Rel(root.Path, item.path)
+		if err == nil && root.ignore.Match(filepath.ToSlash(rel), true) {
+			return nil
+		}
+	}
+
+	// don't walk a directory twice when following symlinks
+	if root.FollowSymlinks {
+		resolved, err := filepath.EvalSymlinks(item.path)
+		if err != nil {
+			return nil
+		}
+		w.mu.Lock()
+		seen := root.visited[resolved]
+		root.visited[resolved] = true
+		w.mu.Unlock()
+		if seen {
+			return nil
+		}
+	}
+
+	if item.parent != nil {
+		return w.visitNested(item)
+	}
+
+	// check if any rooter pattern exists in this directory,
+	// projects above the minimum depth are passed over
+	project := hasRooter(item.path, root.rooterPatterns)
+	if item.depth >= root.MinDepth && project {
+		// removing the rooter pattern changes the mtime of the project
+		if w.dirs != nil {
+			w.record(item.path)
+		}
+		label := strings.ReplaceAll(item.path, root.Path+"/", "")
+		if !w.foundProject(root, label, item.path) || len(root.nestedRooterPatterns) == 0 {
+			// don't extends search breadth
+			// that stops from build directories or sub-Projects
+			// from being picked up
+			return nil
+		}
+
+		// unless asked to look for sub-projects
+		return w.children(item, &model.Entry{Label: label, Path: item.path}, true)
+	}
+
+	// new projects in or below this directory change its mtime
+	if w.dirs != nil {
+		w.record(item.path)
+	}
+
+	if root.MaxDepth > 0 && item.depth >= root.MaxDepth {
+		return nil
+	}
+
+	// projects passed over are searched for deeper ones, but not their git
+	// directory
+	return w.children(item, nil, project)
+}
+
+// visitNested checks a directory inside a project for a nested rooter
+// pattern. A match becomes a sub-project labelled below the project and is not
+// descended into. Depth limits don't apply inside a project.
+func (w *walker) visitNested(item walkItem) []walkItem {
+	if hasRooter(item.path, item.root.nestedRooterPatterns) {
+		rel, err := filepath.Rel(item.parent.Path, item.path)
+		if err != nil {
+			return nil
+		}
+		label := item.parent.Label + "/" + filepath.ToSlash(rel)
+		if w.dirs != nil {
+			w.record(item.path)
+		}
+		w.found(item.root, model.Entry{Label: label, Path: item.path})
+		return nil
+	}
+
+	if w.dirs != nil {
+		w.record(item.path)
+	}
+	return w.children(item, item.parent, true)
+}
+
+// hasRooter reports whether any of the rooter patterns exists in dir.
+func hasRooter(dir string, rooterPatterns []string) bool {
+	for _, pattern := range rooterPatterns {
+		if _, err := os.Stat(filepath.Join(dir, pattern)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// children returns the subdirectories of item to visit next, below parent
+// when looking for sub-projects. The git directory of a project is never
+// descended into.
+func (w *walker) children(item walkItem, parent *model.Entry, project bool) []walkItem {
+	files, err := os.ReadDir(item.path)
+	if err != nil {
+		return nil
+	}
+
+	children := []walkItem{}
+	for _, file := range files {
+		if project && file.Name() == git.DirName {
+			continue
+		}
+		path := filepath.Join(item.path, file.Name())
+		if file.IsDir() || (item.root.FollowSymlinks && file.Type()&fs.ModeSymlink != 0 && isDir(path)) {
+			children = append(children, walkItem{root: item.root, path: path, depth: item.depth + 1, parent: parent})
+		}
+	}
+	return children
+}
+
+// isDir reports whether path is, or links to, a directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// foundProject records a project, and its linked worktrees if enabled. Linked
+// worktrees found while walking are skipped, they are offered through their
+// repository, see adoptWorktrees. It reports whether the project was recorded.
+func (w *walker) foundProject(root *walkRoot, label string, path string) bool {
+	if !w.worktrees {
+		w.found(root, model.Entry{Label: label, Path: path})
+		return true
+	}
+	if git.IsLinkedWorktree(path) {
+		w.mu.Lock()
+		w.skipped = append(w.skipped, skippedWorktree{root: root, entry: model.Entry{Label: label, Path: path}})
+		w.mu.Unlock()
+		return false
+	}
+
+	w.found(root, model.Entry{Label: label, Path: path})
+
+	gitDir, err := git.GitDir(path)
+	if err != nil {
+		return true
+	}
+	if w.dirs != nil {
+		// adding or removing worktrees changes their metadata directory,
+		// checking out another branch rewrites their HEAD
+		w.record(git.WorktreesDir(gitDir))
+	}
+	worktrees, err := git.Worktrees(gitDir)
+	if err != nil {
+		return true
+	}
+	for _, worktree := range worktrees {
+		if w.dirs != nil {
+			w.record(filepath.Join(git.WorktreesDir(gitDir), worktree.Name, "HEAD"))
+		}
+		w.found(root, model.Entry{Label: label + "@" + worktree.Branch, Path: worktree.Path})
+	}
+	return true
+}
+
+// adoptWorktrees records the linked worktrees skipped while walking that none
+// of the repositories found offers, because their repository lies outside of
+// the search directories or is ignored. They are offered as projects of their
+// own instead.
+func (w *walker) adoptWorktrees(scans []*walkRoot) {
+	offered := map[string]bool{}
+	for _, scan := range scans {
+		for _, entry := range scan.entries {
+			offered[resolvePath(entry.Path)] = true
+		}
+	}
+	for _, skipped := range w.skipped {
+		if !offered[resolvePath(skipped.entry.Path)] {
+			w.found(skipped.root, skipped.entry)
+		}
+	}
+}
+
+// resolvePath returns path with symlinks resolved, worktrees are listed by
+// git under their real path.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// record stores the current mtime of path in dirs.
+func (w *walker) record(path string) {
+	mtime := modTime(path)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dirs[path] = mtime
+}
+
+// found records a project of root and passes it on to emit.
+func (w *walker) found(root *walkRoot, entry model.Entry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	root.entries = append(root.entries, entry)
+	if w.emit != nil {
+		w.emit(entry)
+	}
+}
+
+// sortEntries orders entries by path, comparing element by element so that
+// a directory sorts directly before its own subdirectories, as in a walk.
+func sortEntries(entries []model.Entry) {
+	slices.SortFunc(entries, func(a, b model.Entry) int {
+		return slices.Compare(strings.Split(a.Path, string(filepath.Separator)), strings.Split(b.Path, string(filepath.Separator)))
+	})
+}
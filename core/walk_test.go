@@ -0,0 +1,291 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/oschrenk/sessionizer/model"
+)
+
+func TestWalkDirs(t *testing.T) {
+	root := t.TempDir()
+	other := t.TempDir()
+	for _, p := range []string{
+		filepath.Join(root, "b/repo/.git"),
+		filepath.Join(root, "a-c/.git"),
+		filepath.Join(root, "a/b/.git"),
+		filepath.Join(root, "a/b/nested/.git"),
+		filepath.Join(root, "node_modules/dep/.git"),
+		filepath.Join(root, "deep/er/and/deeper/.git"),
+		filepath.Join(other, "x/.git"),
+	} {
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	labels := func(entries []model.Entry) []string {
+		result := []string{}
+		for _, e := range entries {
+			result = append(result, e.Label)
+		}
+		return result
+	}
+
+	for _, workers := range []int{0, 1, 4, 64} {
+		config := model.Config{
+			SearchDirs:     []model.SearchDir{{Path: root}, {Path: other}, {Path: filepath.Join(root, "missing")}},
+			Ignore:         []string{"node_modules"},
+			RooterPatterns: []string{".git"},
+			Workers:        workers,
+		}
+		emitted := 0
+		results := walkDirs(config, nil, func(model.Entry) { emitted++ })
+		if len(results) != 3 {
+			t.Fatalf("workers=%d: got %d results, want 3", workers, len(results))
+		}
+
+		want := []string{"a/b", "a-c", "b/repo", "deep/er/and/deeper"}
+		if got := labels(results[0]); !slices.Equal(got, want) {
+			t.Errorf("workers=%d: root labels = %v, want %v", workers, got, want)
+		}
+		if got := labels(results[1]); !slices.Equal(got, []string{"x"}) {
+			t.Errorf("workers=%d: other labels = %v, want [x]", workers, got)
+		}
+		if emitted != 5 {
+			t.Errorf("workers=%d: emitted %d entries, want 5", workers, emitted)
+		}
+		if len(results[2]) != 0 {
+			t.Errorf("workers=%d: missing root returned %v", workers, results[2])
+		}
+	}
+}
+
+func TestWalkDirsIgnore(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{"org/vendor/.git", "org/app/.git", "build-1/.git", "build-keep/.git", "archive/old/.git"} {
+		if err := os.MkdirAll(filepath.Join(root, p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".sessionizerignore"), []byte("/archive\n!build-keep\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		ignoreFiles bool
+		want        []string
+	}{
+		{name: "without ignore file", want: []string{"archive/old", "org/app"}},
+		{name: "with ignore file", ignoreFiles: true, want: []string{"build-keep", "org/app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := model.Config{
+				SearchDirs:     []model.SearchDir{{Path: root}},
+				Ignore:         []string{"**/vendor", "build-*"},
+				IgnoreFiles:    tt.ignoreFiles,
+				RooterPatterns: []string{".git"},
+			}
+			dirs := map[string]int64{}
+			got := []string{}
+			for _, e := range walkDirs(config, dirs, nil)[0] {
+				got = append(got, e.Label)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("walkDirs() = %v, want %v", got, tt.want)
+			}
+			_, recorded := dirs[filepath.Join(root, ".sessionizerignore")]
+			if recorded != tt.ignoreFiles {
+				t.Errorf("ignore file recorded = %v, want %v", recorded, tt.ignoreFiles)
+			}
+		})
+	}
+}
+
+func TestWalkDirsDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{".git/objects/ab", "one/.git/objects/cd", "org/two/.git", "org/team/three/.git", "linked/.git"} {
+		if err := os.MkdirAll(filepath.Join(root, p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	elsewhere := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(elsewhere, "target/.git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// a link out of the tree and one back to its root
+	if err := os.Symlink(elsewhere, filepath.Join(root, "org/external")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "org/loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  model.SearchDir
+		want []string
+	}{
+		{name: "root is a project", dir: model.SearchDir{}, want: []string{root}},
+		{name: "min depth", dir: model.SearchDir{MinDepth: 1}, want: []string{"linked", "one", "org/team/three", "org/two"}},
+		{name: "min and max depth", dir: model.SearchDir{MinDepth: 1, MaxDepth: 2}, want: []string{"linked", "one", "org/two"}},
+		{name: "min depth only deep", dir: model.SearchDir{MinDepth: 3}, want: []string{"org/team/three"}},
+		{name: "follow symlinks", dir: model.SearchDir{MinDepth: 1, FollowSymlinks: true}, want: []string{"linked", "one", "org/external/target", "org/team/three", "org/two"}},
+		{name: "own ignore", dir: model.SearchDir{MinDepth: 1, Ignore: []string{"team"}}, want: []string{"linked", "one", "org/two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.dir.Path = root
+			config := model.Config{
+				SearchDirs:     []model.SearchDir{tt.dir},
+				RooterPatterns: []string{".git"},
+			}
+			dirs := map[string]int64{}
+			got := []string{}
+			for _, e := range walkDirs(config, dirs, nil)[0] {
+				got = append(got, e.Label)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("walkDirs() = %v, want %v", got, tt.want)
+			}
+			// projects passed over by the minimum depth are searched, their
+			// git directories are not
+			for dir := range dirs {
+				if slices.Contains(strings.Split(dir, string(filepath.Separator)), ".git") {
+					t.Errorf("walkDirs() recorded %s", dir)
+				}
+			}
+		})
+	}
+}
+
+func TestWalkDirsWorktrees(t *testing.T) {
+	root := t.TempDir()
+	write := func(path string, content string) {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// org/repo with a linked worktree next to it, inside the search directory
+	write("org/repo/.git/HEAD", "ref: refs/heads/main\n")
+	write("org/repo/.git/worktrees/fix/HEAD", "ref: refs/heads/fix\n")
+	write("org/repo/.git/worktrees/fix/gitdir", filepath.Join(root, "org/repo-fix/.git")+"\n")
+	write("org/repo-fix/.git", "gitdir: "+filepath.Join(root, "org/repo/.git/worktrees/fix")+"\n")
+
+	// other/repo-feat is a linked worktree of a repository outside the search
+	// directory
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(outside, "repo/.git/worktrees/feat"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "repo/.git/worktrees/feat/HEAD"), []byte("ref: refs/heads/feat\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	write("other/repo-feat/.git", "gitdir: "+filepath.Join(outside, "repo/.git/worktrees/feat")+"\n")
+
+	tests := []struct {
+		name      string
+		worktrees bool
+		want      []string
+	}{
+		{name: "disabled", want: []string{"org/repo", "org/repo-fix", "other/repo-feat"}},
+		{name: "enabled", worktrees: true, want: []string{"org/repo", "org/repo@fix", "other/repo-feat"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := model.Config{
+				SearchDirs:     []model.SearchDir{{Path: root}},
+				RooterPatterns: []string{".git"},
+				Worktrees:      tt.worktrees,
+			}
+			got := []string{}
+			for _, e := range walkDirs(config, nil, nil)[0] {
+				got = append(got, e.Label)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("walkDirs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkDirsNested(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{
+		"mono/.git/modules/lib",
+		"mono/services/api",
+		"mono/services/web/src/widget",
+		"mono/node_modules/dep",
+		"plain/.git",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{
+		"mono/go.mod",
+		"mono/.git/modules/lib/go.mod",
+		"mono/services/api/go.mod",
+		"mono/services/web/package.json",
+		"mono/services/web/src/widget/package.json",
+		"mono/node_modules/dep/package.json",
+	} {
+		if err := os.WriteFile(filepath.Join(root, p), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		config model.Config
+		want   []string
+	}{
+		{
+			name: "disabled",
+			want: []string{"mono", "plain"},
+		},
+		{
+			name: "global patterns",
+			config: model.Config{
+				NestedRooterPatterns: []string{"go.mod", "package.json"},
+			},
+			want: []string{"mono", "mono/services/api", "mono/services/web", "plain"},
+		},
+		{
+			name: "directory patterns replace global ones",
+			config: model.Config{
+				SearchDirs:           []model.SearchDir{{NestedRooterPatterns: []string{"package.json"}}},
+				NestedRooterPatterns: []string{"go.mod"},
+			},
+			want: []string{"mono", "mono/services/web", "plain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if len(config.SearchDirs) == 0 {
+				config.SearchDirs = []model.SearchDir{{}}
+			}
+			config.SearchDirs[0].Path = root
+			config.Ignore = []string{"node_modules"}
+			config.RooterPatterns = []string{".git"}
+
+			got := []string{}
+			for _, e := range walkDirs(config, nil, nil)[0] {
+				got = append(got, e.Label)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("walkDirs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
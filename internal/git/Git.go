@@ -0,0 +1,155 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/oschrenk/sessionizer/internal/shell"
+)
+
+// DirName is the name of the git directory, or git file, in a work tree.
+const DirName = ".git"
+
+// shortHashLength is the length of abbreviated commit hashes
+const shortHashLength = 7
+
+// Worktree is a linked work tree of a repository.
+type Worktree struct {
+	// Name identifies the worktree in the git directory of the repository
+	Name   string
+	Path   string
+	Branch string
+}
+
+// GitDir returns the git directory of the work tree at path: path/.git if it
+// is a directory, or the directory a .git file points to.
+func GitDir(path string) (string, error) {
+	dotGit := filepath.Join(path, DirName)
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+	return readGitFile(dotGit)
+}
+
+// readGitFile resolves a .git file of the form "gitdir: <path>".
+func readGitFile(dotGit string) (string, error) {
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", errors.New("invalid git file: " + dotGit)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(dotGit), gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// IsLinkedWorktree reports whether path is a linked worktree of a repository,
+// whose .git is a file pointing into the worktrees of the main repository.
+func IsLinkedWorktree(path string) bool {
+	dotGit := filepath.Join(path, DirName)
+	info, err := os.Stat(dotGit)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	gitDir, err := readGitFile(dotGit)
+	if err != nil {
+		return false
+	}
+	return filepath.Base(filepath.Dir(gitDir)) == "worktrees"
+}
+
+// Head returns the branch checked out according to the HEAD file in gitDir,
+// or the abbreviated commit hash if HEAD is detached.
+func Head(gitDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref:"); ok {
+		ref = strings.TrimSpace(ref)
+		return strings.TrimPrefix(ref, "refs/heads/"), nil
+	}
+	if len(head) > shortHashLength {
+		head = head[:shortHashLength]
+	}
+	return head, nil
+}
+
+// Branch returns the branch checked out in the work tree at path.
+func Branch(path string) (string, error) {
+	gitDir, err := GitDir(path)
+	if err != nil {
+		return "", err
+	}
+	return Head(gitDir)
+}
+
+// WorktreesDir returns the directory where the repository with the git
+// directory gitDir keeps the metadata of its linked worktrees.
+func WorktreesDir(gitDir string) string {
+	return filepath.Join(gitDir, "worktrees")
+}
+
+// Worktrees lists the linked worktrees of the repository with the git
+// directory gitDir, sorted by name. Worktrees whose directory no longer
+// exists are left out.
+func Worktrees(gitDir string) ([]Worktree, error) {
+	entries, err := os.ReadDir(WorktreesDir(gitDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	worktrees := []Worktree{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		metaDir := filepath.Join(WorktreesDir(gitDir), entry.Name())
+
+		// gitdir holds the path of the .git file in the worktree
+		data, err := os.ReadFile(filepath.Join(metaDir, "gitdir"))
+		if err != nil {
+			continue
+		}
+		path := filepath.Dir(strings.TrimSpace(string(data)))
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		branch, err := Head(metaDir)
+		if err != nil {
+			continue
+		}
+		worktrees = append(worktrees, Worktree{Name: entry.Name(), Path: path, Branch: branch})
+	}
+
+	slices.SortFunc(worktrees, func(a, b Worktree) int { return strings.Compare(a.Name, b.Name) })
+	return worktrees, nil
+}
+
+// Dirty reports whether the work tree at path has uncommitted changes,
+// including untracked files. Unlike the other functions it runs git, as
+// comparing the work tree against the index is not feasible from here.
+func Dirty(path string) (bool, error) {
+	out, _, err := shell.Run("git", []string{"-C", path, "status", "--porcelain"})
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
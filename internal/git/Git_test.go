@@ -0,0 +1,98 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile creates a file and its parent directories.
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeRepo lays out a repository with linked worktrees the way git does.
+func fakeRepo(t *testing.T) (repo string, feature string, detached string) {
+	base := t.TempDir()
+	repo = filepath.Join(base, "repo")
+	feature = filepath.Join(base, "repo-feature")
+	detached = filepath.Join(base, "repo-detached")
+	gitDir := filepath.Join(repo, DirName)
+
+	writeFile(t, filepath.Join(gitDir, "HEAD"), "ref: refs/heads/main\n")
+
+	writeFile(t, filepath.Join(gitDir, "worktrees/feature/HEAD"), "ref: refs/heads/feature/login\n")
+	writeFile(t, filepath.Join(gitDir, "worktrees/feature/gitdir"), filepath.Join(feature, DirName)+"\n")
+	writeFile(t, filepath.Join(feature, DirName), "gitdir: "+filepath.Join(gitDir, "worktrees/feature")+"\n")
+
+	writeFile(t, filepath.Join(gitDir, "worktrees/detached/HEAD"), "0123456789abcdef0123456789abcdef01234567\n")
+	writeFile(t, filepath.Join(gitDir, "worktrees/detached/gitdir"), filepath.Join(detached, DirName)+"\n")
+	writeFile(t, filepath.Join(detached, DirName), "gitdir: "+filepath.Join(gitDir, "worktrees/detached")+"\n")
+
+	// a worktree whose directory was deleted without pruning
+	writeFile(t, filepath.Join(gitDir, "worktrees/gone/HEAD"), "ref: refs/heads/gone\n")
+	writeFile(t, filepath.Join(gitDir, "worktrees/gone/gitdir"), filepath.Join(base, "gone", DirName)+"\n")
+
+	return repo, feature, detached
+}
+
+func TestWorktrees(t *testing.T) {
+	repo, feature, detached := fakeRepo(t)
+
+	gitDir, err := GitDir(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktrees, err := Worktrees(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Worktree{
+		{Name: "detached", Path: detached, Branch: "0123456"},
+		{Name: "feature", Path: feature, Branch: "feature/login"},
+	}
+	if len(worktrees) != len(want) {
+		t.Fatalf("Worktrees() = %v, want %v", worktrees, want)
+	}
+	for i := range want {
+		if worktrees[i] != want[i] {
+			t.Errorf("Worktrees()[%d] = %v, want %v", i, worktrees[i], want[i])
+		}
+	}
+}
+
+func TestBranch(t *testing.T) {
+	repo, feature, _ := fakeRepo(t)
+
+	tests := []struct {
+		path   string
+		want   string
+		linked bool
+	}{
+		{path: repo, want: "main", linked: false},
+		{path: feature, want: "feature/login", linked: true},
+	}
+	for _, tt := range tests {
+		got, err := Branch(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Branch(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+		if linked := IsLinkedWorktree(tt.path); linked != tt.linked {
+			t.Errorf("IsLinkedWorktree(%s) = %v, want %v", tt.path, linked, tt.linked)
+		}
+	}
+
+	if _, err := Branch(t.TempDir()); err == nil {
+		t.Error("Branch() of a directory without git returned no error")
+	}
+}
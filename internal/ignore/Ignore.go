@@ -0,0 +1,148 @@
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// FileName is the name of the ignore file honored in search directories.
+const FileName = ".sessionizerignore"
+
+// pattern is a single parsed gitignore-style rule
+type pattern struct {
+	// segments of the pattern split at "/", "**" matches any number of segments
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// Matcher decides whether a path is ignored, using gitignore semantics:
+//   - blank lines and lines starting with # are skipped
+//   - a leading ! re-includes paths excluded by an earlier pattern
+//   - a trailing / only matches directories
+//   - a pattern without a / in the middle or at the start matches the name at
+//     any depth, otherwise it is anchored to the base directory
+//   - *, ? and [...] match within a single path element, ** matches across them
+//
+// The last matching pattern decides.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New creates a matcher from pattern lines.
+func New(lines ...string) *Matcher {
+	m := &Matcher{}
+	m.Add(lines...)
+	return m
+}
+
+// Add appends pattern lines, they take precedence over the existing ones.
+func (m *Matcher) Add(lines ...string) {
+	for _, line := range lines {
+		if p, ok := parse(line); ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+}
+
+// ReadFile reads pattern lines from an ignore file.
+func ReadFile(filePath string) ([]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func parse(line string) (pattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+
+	// a pattern with a slash is relative to the base directory,
+	// one without matches at any depth
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+
+	p.segments = strings.Split(line, "/")
+	return p, true
+}
+
+// Match reports whether relPath, a slash separated path relative to the base
+// directory, is ignored.
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	segments := strings.Split(strings.Trim(relPath, "/"), "/")
+
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, segments) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path elements against pattern elements, where "**"
+// matches zero or more path elements.
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		// a trailing ** matches everything inside, but not the base itself
+		if len(pattern) == 1 {
+			return len(segments) > 0
+		}
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,62 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{name: "basename at root", patterns: []string{"node_modules"}, path: "node_modules", isDir: true, want: true},
+		{name: "basename at depth", patterns: []string{"node_modules"}, path: "a/b/node_modules", isDir: true, want: true},
+		{name: "basename does not match prefix", patterns: []string{"node_modules"}, path: "node_modules_old", isDir: true, want: false},
+		{name: "glob", patterns: []string{"build-*"}, path: "org/build-arm64", isDir: true, want: true},
+		{name: "glob does not cross separators", patterns: []string{"org*"}, path: "org/x", isDir: true, want: false},
+		{name: "character class", patterns: []string{"tmp[0-9]"}, path: "tmp3", isDir: true, want: true},
+		{name: "double star prefix", patterns: []string{"**/vendor"}, path: "a/b/vendor", isDir: true, want: true},
+		{name: "double star middle", patterns: []string{"a/**/vendor"}, path: "a/vendor", isDir: true, want: true},
+		{name: "double star middle deep", patterns: []string{"a/**/vendor"}, path: "a/x/y/vendor", isDir: true, want: true},
+		{name: "double star suffix", patterns: []string{"archive/**"}, path: "archive/old", isDir: true, want: true},
+		{name: "double star suffix not base", patterns: []string{"archive/**"}, path: "archive", isDir: true, want: false},
+		{name: "anchored", patterns: []string{"/tmp"}, path: "tmp", isDir: true, want: true},
+		{name: "anchored not at depth", patterns: []string{"/tmp"}, path: "a/tmp", isDir: true, want: false},
+		{name: "path is anchored", patterns: []string{"org/archive"}, path: "x/org/archive", isDir: true, want: false},
+		{name: "dir only", patterns: []string{"logs/"}, path: "logs", isDir: false, want: false},
+		{name: "negation", patterns: []string{"build-*", "!build-keep"}, path: "build-keep", isDir: true, want: false},
+		{name: "last match wins", patterns: []string{"!build-keep", "build-*"}, path: "build-keep", isDir: true, want: true},
+		{name: "comment and blank", patterns: []string{"# tmp", "", "  "}, path: "# tmp", isDir: true, want: false},
+		{name: "escaped hash", patterns: []string{`\#tmp`}, path: "#tmp", isDir: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.patterns...).Match(tt.path, tt.isDir)
+			if got != tt.want {
+				t.Errorf("Match(%q) with %v = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(file, []byte("# comment\nvendor\n!vendor/keep\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"# comment", "vendor", "!vendor/keep"}; !slices.Equal(lines, want) {
+		t.Errorf("ReadFile() = %v, want %v", lines, want)
+	}
+}
@@ -4,10 +4,22 @@ import (
 	"bytes"
 	"os"
 	"os/exec"
+	"strings"
 )
 
+// Quote quotes s as a single word for POSIX shells
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // Run a command and capture stdout, stderr
 func Run(name string, args []string) (string, string, error) {
+	return RunIn("", name, args)
+}
+
+// RunIn runs a command in the directory dir, the current one if empty, and
+// captures stdout, stderr
+func RunIn(dir string, name string, args []string) (string, string, error) {
 	// find executable in PATH, and get absolute path
 	bin, err := exec.LookPath(name)
 	if err != nil {
@@ -16,6 +28,7 @@ func Run(name string, args []string) (string, string, error) {
 
 	// prepare command
 	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
 
 	// prepare capture stdout, stderr
 	var stdout, stderr bytes.Buffer
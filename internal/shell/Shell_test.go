@@ -0,0 +1,17 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	for _, s := range []string{"", "plain", "with space", "it's", `"$HOME" \n`, "'"} {
+		stdout, _, err := Run("sh", []string{"-c", "printf %s " + Quote(s)})
+		if err != nil {
+			t.Fatalf("Quote(%q): %v", s, err)
+		}
+		if stdout != s {
+			t.Errorf("Quote(%q) evaluates to %q", s, stdout)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package tmux
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/oschrenk/sessionizer/internal/shell"
+)
+
+// queries only read from the server, a dry run answers them without
+// recording them
+var queries = map[string]bool{
+	"capture-pane":    true,
+	"display-message": true,
+	"has-session":     true,
+	"list-clients":    true,
+	"list-panes":      true,
+	"list-sessions":   true,
+	"list-windows":    true,
+}
+
+// formatPattern matches a variable in a tmux format
+var formatPattern = regexp.MustCompile(`#\{(\w+)\}`)
+
+// safeArgPattern matches arguments that need no quoting in a shell
+var safeArgPattern = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// Plan records the tmux commands of a dry run. It answers them like a server
+// without sessions would, with placeholder IDs for everything created.
+type Plan struct {
+	// Commands holds every command that changes the server, in order, each
+	// with the program name and its arguments
+	Commands [][]string
+
+	mu       sync.Mutex
+	sessions int
+	windows  int
+	panes    int
+	// first window of each session, first pane of each window
+	firstWindow map[string]string
+	firstPane   map[string]string
+}
+
+// DryRun returns a server that records its commands in plan instead of
+// running them.
+func DryRun(plan *Plan) *Server {
+	return &Server{plan: plan}
+}
+
+// Plan returns the plan of a dry run, nil if the server runs commands.
+func (s *Server) Plan() *Plan {
+	return s.plan
+}
+
+// Add records a command that is not run by tmux, like a script.
+func (p *Plan) Add(name string, args ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Commands = append(p.Commands, append([]string{name}, args...))
+}
+
+// String lists the commands one per line, quoted for a shell.
+func (p *Plan) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var b strings.Builder
+	for _, command := range p.Commands {
+		for i, arg := range command {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			if !safeArgPattern.MatchString(arg) {
+				arg = shell.Quote(arg)
+			}
+			b.WriteString(arg)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// answer records a command, unless it is a query, and returns what tmux would
+// print for it.
+func (p *Plan) answer(args []string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.firstWindow == nil {
+		p.firstWindow = map[string]string{}
+		p.firstPane = map[string]string{}
+	}
+
+	if !queries[args[0]] {
+		p.Commands = append(p.Commands, append([]string{"tmux"}, withSocket(args)...))
+	}
+
+	vars := map[string]string{}
+	switch args[0] {
+	case "has-session":
+		// a dry run plans as if the session did not exist yet
+		return "", errors.New("no session in a dry run")
+	case "new-session":
+		p.sessions++
+		sessionId := fmt.Sprintf("$%d", p.sessions)
+		windowId, _ := p.newWindow()
+		p.firstWindow[sessionId] = windowId
+		vars["session_id"] = sessionId
+		vars["session_name"] = flagValue(args, "-s")
+		vars["session_path"] = flagValue(args, "-c")
+	case "new-window":
+		vars["window_id"], _ = p.newWindow()
+	case "split-window":
+		p.panes++
+		vars["pane_id"] = fmt.Sprintf("%%%d", p.panes)
+	case "list-windows":
+		windowId := p.firstWindow[flagValue(args, "-t")]
+		if windowId == "" {
+			return "", nil
+		}
+		vars["window_id"] = windowId
+	case "list-panes":
+		paneId := p.firstPane[flagValue(args, "-t")]
+		if paneId == "" {
+			return "", nil
+		}
+		vars["pane_id"] = paneId
+	}
+
+	format := flagValue(args, "-F")
+	if format == "" {
+		return "", nil
+	}
+	out := formatPattern.ReplaceAllStringFunc(format, func(variable string) string {
+		return vars[formatPattern.FindStringSubmatch(variable)[1]]
+	})
+	return out + "\n", nil
+}
+
+// newWindow hands out the IDs of a new window and its first pane.
+func (p *Plan) newWindow() (string, string) {
+	p.windows++
+	p.panes++
+	windowId := fmt.Sprintf("@%d", p.windows)
+	paneId := fmt.Sprintf("%%%d", p.panes)
+	p.firstPane[windowId] = paneId
+	return windowId, paneId
+}
+
+// flagValue returns the value following flag in args, empty if it is missing.
+func flagValue(args []string, flag string) string {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
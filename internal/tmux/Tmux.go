@@ -15,30 +15,46 @@ const dot = "."
 const dash = "-"
 const space = " "
 
+// sessionFormat lists the fields of a session, the path goes last as it may
+// contain the separator itself.
+const sessionFormat = "#{session_id}:#{session_attached}:#{session_last_attached}:#{session_activity}:#{session_name}:#{session_path}"
+
+// windowFormat lists the fields of a window, the name goes last as it may
+// contain the separator.
+const windowFormat = "#{window_id}:#{window_index}:#{window_active}:#{window_active_clients}:#{window_layout}:#{window_name}"
+
+// paneFormat lists the fields of a pane, the path goes last as it may contain
+// the separator.
+const paneFormat = "#{pane_id}:#{pane_index}:#{pane_active}:#{pane_pid}:#{pane_current_command}:#{pane_current_path}"
+
 // shallowSession represents a session without its windows populated.
 // Used internally for parsing tmux output before hydration.
 type shallowSession struct {
-	Id       string
-	Name     string
-	Attached bool
-	Path     string
+	Id           string
+	Name         string
+	Attached     bool
+	LastAttached int64
+	Activity     int64
+	Path         string
 }
 
 // shallowWindow represents a window without its panes populated.
 // Used internally for parsing tmux output before hydration.
 type shallowWindow struct {
 	Id            string
+	Index         int
 	Active        bool
 	ActiveClients int
+	Layout        string
 	Name          string
 }
 
-// normalizeName converts a session name to a tmux-safe
+// NormalizeName converts a session name to a tmux-safe
 // format y replacing problematic characters
 // - (colons, spaces, dots) with dashes and converting to lowercase.
 //
 // This prevents issues with tmux's session name parsing which uses colon as a separator.
-func normalizeName(name string) string {
+func NormalizeName(name string) string {
 	name = strings.ReplaceAll(name, sessionSeparator, dash)
 	name = strings.ReplaceAll(name, space, dash)
 	name = strings.ReplaceAll(name, dot, dash)
@@ -65,6 +81,16 @@ func run(args []string) (string, string, error) {
 	return shell.Run("tmux", withSocket(args))
 }
 
+// run runs a tmux command against the server, in a dry run it is only
+// recorded and answered by the plan
+func (s *Server) run(args []string) (string, string, error) {
+	if s.plan != nil {
+		out, err := s.plan.answer(args)
+		return out, "", err
+	}
+	return run(args)
+}
+
 // runInteractive is the interactive twin of run: it hands the real terminal to
 // tmux instead of capturing its output, so an `attach` can take over the
 // terminal. It returns when tmux exits (e.g. when the user detaches).
@@ -73,33 +99,54 @@ func runInteractive(args []string) error {
 }
 
 func parseSession(line string) (shallowSession, bool) {
-	result := strings.Split(line, sessionSeparator)
-	if len(result) != 4 {
+	result := strings.SplitN(line, sessionSeparator, 6)
+	if len(result) != 6 {
 		return shallowSession{}, false
 	}
 	id := result[0]
-	name := result[1]
-	attached, _ := strconv.ParseBool(result[2])
-	path := result[3]
-
-	session := shallowSession{Id: id, Name: name, Attached: attached, Path: path}
+	attached, _ := strconv.ParseBool(result[1])
+	// empty for sessions that were never attached
+	lastAttached, _ := strconv.ParseInt(result[2], 10, 64)
+	activity, _ := strconv.ParseInt(result[3], 10, 64)
+	name := result[4]
+	path := result[5]
+
+	session := shallowSession{Id: id, Name: name, Attached: attached, LastAttached: lastAttached, Activity: activity, Path: path}
 	return session, true
 }
 
 func parseWindow(line string) (shallowWindow, bool) {
-	result := strings.Split(line, windowSeparator)
-	if len(result) != 4 {
+	result := strings.SplitN(line, windowSeparator, 6)
+	if len(result) != 6 {
 		return shallowWindow{}, false
 	}
 	id := result[0]
-	active, _ := strconv.ParseBool(result[1])
-	activeClient, _ := strconv.Atoi(result[2])
-	name := result[3]
+	index, _ := strconv.Atoi(result[1])
+	active, _ := strconv.ParseBool(result[2])
+	activeClient, _ := strconv.Atoi(result[3])
+	layout := result[4]
+	name := result[5]
 
-	window := shallowWindow{Id: id, Active: active, ActiveClients: activeClient, Name: name}
+	window := shallowWindow{Id: id, Index: index, Active: active, ActiveClients: activeClient, Layout: layout, Name: name}
 	return window, true
 }
 
+func parsePane(line string) (Pane, bool) {
+	result := strings.SplitN(line, ":", 6)
+	if len(result) != 6 {
+		return Pane{}, false
+	}
+	id := result[0]
+	index, _ := strconv.Atoi(result[1])
+	active, _ := strconv.ParseBool(result[2])
+	pid, _ := strconv.Atoi(result[3])
+	command := result[4]
+	path := result[5]
+
+	pane := Pane{Id: id, Index: index, Active: active, Pid: pid, Command: command, Path: path}
+	return pane, true
+}
+
 // hydrateSession converts a shallowSession to a full Session by fetching its windows.
 func (s *Server) hydrateSession(shallow shallowSession) (Session, error) {
 	windows, err := s.ListWindows(shallow.Id)
@@ -108,11 +155,13 @@ func (s *Server) hydrateSession(shallow shallowSession) (Session, error) {
 	}
 
 	return Session{
-		Id:       shallow.Id,
-		Name:     shallow.Name,
-		Attached: shallow.Attached,
-		Path:     shallow.Path,
-		Windows:  windows,
+		Id:           shallow.Id,
+		Name:         shallow.Name,
+		Attached:     shallow.Attached,
+		LastAttached: shallow.LastAttached,
+		Activity:     shallow.Activity,
+		Path:         shallow.Path,
+		Windows:      windows,
 	}, nil
 }
 
@@ -125,20 +174,40 @@ func (s *Server) hydrateWindow(shallow shallowWindow) (Window, error) {
 
 	return Window{
 		Id:            shallow.Id,
+		Index:         shallow.Index,
 		Active:        shallow.Active,
 		ActiveClients: shallow.ActiveClients,
+		Layout:        shallow.Layout,
 		Name:          shallow.Name,
 		Panes:         panes,
 	}, nil
 }
 
-func (*Server) currentSessionId() (string, error) {
+// Inside reports whether this process runs inside a session of this server.
+// $TMUX alone is not enough, it may belong to another server than the one
+// selected with a socket name.
+func (s *Server) Inside() bool {
+	env := os.Getenv("TMUX")
+	if env == "" {
+		return false
+	}
+	// $TMUX is "<socket path>,<server pid>,<session index>"
+	socketPath, _, _ := strings.Cut(env, ",")
+
+	out, _, err := s.run([]string{"display-message", "-p", "#{socket_path}"})
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(out) == socketPath
+}
+
+func (s *Server) currentSessionId() (string, error) {
 	args := []string{
 		"display-message",
 		"-p",
 		"#{session_id}"}
 
-	out, _, err := run(args)
+	out, _, err := s.run(args)
 	if err != nil {
 		return "", err
 	}
@@ -147,8 +216,6 @@ func (*Server) currentSessionId() (string, error) {
 }
 
 func listSessions(detachedOnly bool, sessionId string) ([]shallowSession, error) {
-	const sessionFormat = "#{session_id}:#{session_name}:#{session_attached}:#{session_path}"
-
 	args := []string{
 		"list-sessions",
 		"-F",
@@ -201,15 +268,13 @@ func (s *Server) CurrentSession() (Session, error) {
 
 // CurrentWindow returns the currently active window
 func (s *Server) CurrentWindow() (Window, error) {
-	const windowFormat = "#{window_id}:#{window_active}:#{window_active_clients}:#{window_name}"
-
 	args := []string{
 		"display-message",
 		"-p",
 		windowFormat,
 	}
 
-	out, _, err := run(args)
+	out, _, err := s.run(args)
 	if err != nil {
 		return Window{}, err
 	}
@@ -248,9 +313,9 @@ func (s *Server) ListWindows(sessionId string) ([]Window, error) {
 		"-t",
 		sessionId,
 		"-F",
-		"#{window_id}:#{window_active}:#{window_active_clients}:#{window_name}"}
+		windowFormat}
 
-	out, _, err := run(args)
+	out, _, err := s.run(args)
 	if err != nil {
 		return nil, err
 	}
@@ -276,15 +341,15 @@ func (s *Server) ListWindows(sessionId string) ([]Window, error) {
 }
 
 // Lists all panes in the targeted window
-func (*Server) ListPanes(targetWindow string) ([]Pane, error) {
+func (s *Server) ListPanes(targetWindow string) ([]Pane, error) {
 	args := []string{
 		"list-panes",
 		"-t",
 		targetWindow,
 		"-F",
-		"#{pane_id}:#{pane_index}:#{pane_active}"}
+		paneFormat}
 
-	out, _, err := run(args)
+	out, _, err := s.run(args)
 	if err != nil {
 		return nil, err
 	}
@@ -293,27 +358,25 @@ func (*Server) ListPanes(targetWindow string) ([]Pane, error) {
 	panes := []Pane{}
 
 	for _, line := range lines {
-		result := strings.Split(line, ":")
-		if len(result) != 3 {
+		pane, ok := parsePane(line)
+		if !ok {
 			continue
 		}
-		id := result[0]
-		index, _ := strconv.Atoi(result[1])
-		active, _ := strconv.ParseBool(result[2])
-
-		pane := Pane{Id: id, Index: index, Active: active}
-
 		panes = append(panes, pane)
 	}
 
 	return panes, nil
 }
 
-// Creates a new window with the given name and starting directory
+// Creates a new window with the given name and starting directory.
+// The target is a session name or ID followed by a colon, to add the window
+// at the end of that session, optionally followed by the window index to use.
 // Returns the unique window ID assigned by tmux
-func (*Server) AddWindow(name string, path string) (string, error) {
+func (s *Server) AddWindow(target string, name string, path string) (string, error) {
 	args := []string{
 		"new-window",
+		"-t",
+		target,
 		"-n",
 		name,
 		"-c",
@@ -322,29 +385,74 @@ func (*Server) AddWindow(name string, path string) (string, error) {
 		"-F",
 		"#{window_id}",
 	}
-	out, _, err := run(args)
+	out, _, err := s.run(args)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(out), nil
 }
 
+// MoveWindow moves a window, the target being a session followed by a colon
+// and the new window index.
+func (s *Server) MoveWindow(sourceWindow string, target string) error {
+	args := []string{
+		"move-window",
+		"-s",
+		sourceWindow,
+		"-t",
+		target,
+	}
+
+	_, _, err := s.run(args)
+	return err
+}
+
+// SetOption sets a session option of the targeted session, or a global
+// option if the target is empty.
+func (s *Server) SetOption(target string, key string, value string) error {
+	args := []string{"set-option"}
+	if target == "" {
+		args = append(args, "-g")
+	} else {
+		args = append(args, "-t", target)
+	}
+	args = append(args, key, value)
+
+	_, _, err := s.run(args)
+	return err
+}
+
+// SetWindowOption sets a window option of the targeted window.
+func (s *Server) SetWindowOption(targetWindow string, key string, value string) error {
+	args := []string{
+		"set-option",
+		"-w",
+		"-t",
+		targetWindow,
+		key,
+		value,
+	}
+
+	_, _, err := s.run(args)
+	return err
+}
+
 // SelectWindow selects (switches to) the specified window.
 // The target can be a window ID, window index, or window name.
-func (*Server) SelectWindow(targetWindow string) error {
+func (s *Server) SelectWindow(targetWindow string) error {
 	args := []string{
 		"select-window",
 		"-t",
 		targetWindow,
 	}
 
-	_, _, err := run(args)
+	_, _, err := s.run(args)
 	return err
 }
 
 // RenameWindow renames the specified window to the given name.
 // The target can be a window ID, window index, or window name.
-func (*Server) RenameWindow(targetWindow string, newName string) error {
+func (s *Server) RenameWindow(targetWindow string, newName string) error {
 	args := []string{
 		"rename-window",
 		"-t",
@@ -352,14 +460,14 @@ func (*Server) RenameWindow(targetWindow string, newName string) error {
 		newName,
 	}
 
-	_, _, err := run(args)
+	_, _, err := s.run(args)
 	return err
 }
 
 // SelectLayout applies a layout to the specified window.
 // The target can be a window ID, window index, or window name.
 // layoutType should be one of: even-horizontal, even-vertical, main-horizontal, main-vertical, tiled.
-func (*Server) SelectLayout(targetWindow string, layoutType string) error {
+func (s *Server) SelectLayout(targetWindow string, layoutType string) error {
 	args := []string{
 		"select-layout",
 		"-t",
@@ -367,26 +475,26 @@ func (*Server) SelectLayout(targetWindow string, layoutType string) error {
 		layoutType,
 	}
 
-	_, _, err := run(args)
+	_, _, err := s.run(args)
 	return err
 }
 
 // SelectPane selects (focuses) the specified pane.
 // The target can be a pane ID, or a pane index.
-func (*Server) SelectPane(targetPane string) error {
+func (s *Server) SelectPane(targetPane string) error {
 	args := []string{
 		"select-pane",
 		"-t",
 		targetPane,
 	}
 
-	_, _, err := run(args)
+	_, _, err := s.run(args)
 	return err
 }
 
 // SendKeys sends keys/commands to the specified pane.
 // Automatically sends Enter (C-m) after the keys.
-func (*Server) SendKeys(targetPane string, keys string) error {
+func (s *Server) SendKeys(targetPane string, keys string) error {
 	args := []string{
 		"send-keys",
 		"-t",
@@ -395,26 +503,46 @@ func (*Server) SendKeys(targetPane string, keys string) error {
 		"C-m",
 	}
 
-	_, _, err := run(args)
+	_, _, err := s.run(args)
 	return err
 }
 
+// CapturePane returns the visible content of the specified pane.
+func (s *Server) CapturePane(targetPane string) (string, error) {
+	args := []string{
+		"capture-pane",
+		"-p",
+		"-t",
+		targetPane,
+	}
+
+	out, _, err := s.run(args)
+	return out, err
+}
+
 // SplitPane splits the specified pane and returns the new pane ID.
-// Direction can be Horizontal (left/right) or Vertical (top/bottom).
-func (*Server) SplitPane(targetPane string, direction Direction, startDirectory string) (string, error) {
+// The options set the direction, starting directory, size and command of the
+// new pane.
+func (s *Server) SplitPane(targetPane string, options SplitOptions) (string, error) {
 	args := []string{
 		"split-window",
 		"-t",
 		targetPane,
 	}
 
-	if direction == Horizontal {
+	if options.Direction == Horizontal {
 		args = append(args, "-h")
 	}
+	if options.Size != "" {
+		args = append(args, "-l", options.Size)
+	}
 
-	args = append(args, "-c", startDirectory, "-P", "-F", "#{pane_id}")
+	args = append(args, "-c", options.StartDirectory, "-P", "-F", "#{pane_id}")
+	if options.Command != "" {
+		args = append(args, options.Command)
+	}
 
-	out, _, err := run(args)
+	out, _, err := s.run(args)
 	if err != nil {
 		return "", err
 	}
@@ -422,16 +550,33 @@ func (*Server) SplitPane(targetPane string, direction Direction, startDirectory
 	return strings.TrimSpace(out), nil
 }
 
+// RespawnPane replaces the process of the specified pane with command, started
+// in path. The running process is killed.
+func (s *Server) RespawnPane(targetPane string, path string, command string) error {
+	args := []string{
+		"respawn-pane",
+		"-k",
+		"-t",
+		targetPane,
+		"-c",
+		path,
+		command,
+	}
+
+	_, _, err := s.run(args)
+	return err
+}
+
 // HasSession checks if a tmux session with the given name exists.
 // Returns true if the session exists, false otherwise.
-func (*Server) HasSession(name string) bool {
+func (s *Server) HasSession(name string) bool {
 	args := []string{
 		"has-session",
 		"-t",
 		name,
 	}
 
-	_, _, err := run(args)
+	_, _, err := s.run(args)
 	return err == nil
 }
 
@@ -439,14 +584,12 @@ func (*Server) HasSession(name string) bool {
 //
 // Returns nil pointer if session not found
 func (s *Server) SessionByName(name string) (*Session, error) {
-	name = normalizeName(name)
+	name = NormalizeName(name)
 
 	if !s.HasSession(name) {
 		return nil, nil
 	}
 
-	const sessionFormat = "#{session_id}:#{session_name}:#{session_attached}:#{session_path}"
-
 	args := []string{
 		"display-message",
 		"-t",
@@ -455,7 +598,7 @@ func (s *Server) SessionByName(name string) (*Session, error) {
 		sessionFormat,
 	}
 
-	out, _, err := run(args)
+	out, _, err := s.run(args)
 	if err != nil {
 		return nil, err
 	}
@@ -473,6 +616,65 @@ func (s *Server) SessionByName(name string) (*Session, error) {
 	return &session, nil
 }
 
+// KillSession destroys the session with exactly the given name, along with
+// all its windows and panes. The name is matched exactly, as tmux would
+// otherwise also accept a unique prefix of another session's name.
+func (s *Server) KillSession(name string) error {
+	args := []string{
+		"kill-session",
+		"-t",
+		"=" + name,
+	}
+
+	_, _, err := s.run(args)
+	return err
+}
+
+// RenameSession renames the targeted session.
+func (s *Server) RenameSession(targetSession string, name string) error {
+	args := []string{
+		"rename-session",
+		"-t",
+		targetSession,
+		name,
+	}
+
+	_, _, err := s.run(args)
+	return err
+}
+
+// ListClients returns the names of the clients attached to the targeted
+// session.
+func (s *Server) ListClients(targetSession string) ([]string, error) {
+	args := []string{
+		"list-clients",
+		"-t",
+		targetSession,
+		"-F",
+		"#{client_name}",
+	}
+
+	out, _, err := s.run(args)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// SwitchClient shows the targeted session in the named client.
+func (s *Server) SwitchClient(client string, targetSession string) error {
+	args := []string{
+		"switch-client",
+		"-c",
+		client,
+		"-t",
+		targetSession,
+	}
+
+	_, _, err := s.run(args)
+	return err
+}
+
 // Add session
 //
 // we should guard against session names containing
@@ -480,10 +682,10 @@ func (s *Server) SessionByName(name string) (*Session, error) {
 // - the char `.`
 //
 // but are problematic, but since we normalize before, we should be fine
-func (s *Server) AddSession(name string, path string) (Session, error) {
-	name = normalizeName(name)
-	const sessionFormat = "#{session_id}:#{session_name}:#{session_attached}:#{session_path}"
-
+//
+// environment holds KEY=VALUE pairs set in the session for all its panes
+func (s *Server) AddSession(name string, path string, environment ...string) (Session, error) {
+	name = NormalizeName(name)
 	args := []string{
 		"new-session",
 		"-d",
@@ -495,7 +697,10 @@ func (s *Server) AddSession(name string, path string) (Session, error) {
 		"-F",
 		sessionFormat,
 	}
-	out, _, err := run(args)
+	for _, env := range environment {
+		args = append(args, "-e", env)
+	}
+	out, _, err := s.run(args)
 	if err != nil {
 		return Session{}, err
 	}
@@ -568,7 +773,15 @@ func switchClient(sessionName string) error {
 //   - Attached: switches to the session using switch-session
 //   - Detached: attaches to the session using attach-session
 //   - Serverless: switches the client to the session using switch-client
+//
+// A dry run plans it as attach-session.
 func (s *Server) AttachSession(session Session) error {
+	// which one depends on the client, a dry run does not look
+	if s.plan != nil {
+		_, _, err := s.run([]string{"attach-session", "-t", session.Name})
+		return err
+	}
+
 	var err error
 	switch getContext() {
 	case Attached:
@@ -2,6 +2,7 @@ package tmux
 
 import (
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -45,3 +46,103 @@ func TestWithSocket(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSession(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want shallowSession
+		ok   bool
+	}{
+		{
+			name: "attached session",
+			line: "$1:1:1700000100:1700000200:org/app:/home/me/org/app",
+			want: shallowSession{Id: "$1", Name: "org/app", Attached: true, LastAttached: 1700000100, Activity: 1700000200, Path: "/home/me/org/app"},
+			ok:   true,
+		},
+		{
+			name: "never attached session",
+			line: "$2:0::1700000200:notes:/tmp",
+			want: shallowSession{Id: "$2", Name: "notes", Activity: 1700000200, Path: "/tmp"},
+			ok:   true,
+		},
+		{
+			name: "path containing the separator",
+			line: "$3:0:0:0:odd:/tmp/a:b",
+			want: shallowSession{Id: "$3", Name: "odd", Path: "/tmp/a:b"},
+			ok:   true,
+		},
+		{
+			name: "too few fields",
+			line: "$4:notes",
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseSession(tt.line)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseSession(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	line := "@3:2:1:0:b25d,80x24,0,0{40x24,0,0,1,39x24,41,0,2}:logs: api"
+	want := shallowWindow{Id: "@3", Index: 2, Active: true, Layout: "b25d,80x24,0,0{40x24,0,0,1,39x24,41,0,2}", Name: "logs: api"}
+
+	got, ok := parseWindow(line)
+	if !ok || got != want {
+		t.Errorf("parseWindow(%q) = %+v, %v, want %+v", line, got, ok, want)
+	}
+}
+
+func TestParsePane(t *testing.T) {
+	line := "%5:1:0:4242:nvim:/home/me/org/app"
+	want := Pane{Id: "%5", Index: 1, Pid: 4242, Command: "nvim", Path: "/home/me/org/app"}
+
+	got, ok := parsePane(line)
+	if !ok || got != want {
+		t.Errorf("parsePane(%q) = %+v, %v, want %+v", line, got, ok, want)
+	}
+	if _, ok := parsePane("%5:1"); ok {
+		t.Errorf("parsePane() accepted a line with too few fields")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	plan := new(Plan)
+	server := DryRun(plan)
+
+	if server.HasSession("api") {
+		t.Errorf("HasSession() = true in a dry run")
+	}
+	session, err := server.AddSession("api", "/home/me/api")
+	if err != nil {
+		t.Fatalf("AddSession() error: %v", err)
+	}
+	if session.Id != "$1" || session.Name != "api" || session.Path != "/home/me/api" {
+		t.Errorf("AddSession() = %+v, want placeholder session", session)
+	}
+	if len(session.Windows) != 1 || session.Windows[0].Id != "@1" || len(session.Windows[0].Panes) != 1 || session.Windows[0].Panes[0].Id != "%1" {
+		t.Fatalf("AddSession() windows = %+v, want placeholder window and pane", session.Windows)
+	}
+	paneId, err := server.SplitPane("%1", SplitOptions{StartDirectory: "/home/me/api"})
+	if err != nil || paneId != "%2" {
+		t.Errorf("SplitPane() = %q, %v, want %%2", paneId, err)
+	}
+	if err := server.SendKeys(paneId, "echo it's"); err != nil {
+		t.Errorf("SendKeys() error: %v", err)
+	}
+
+	// queries are answered but not planned
+	if len(plan.Commands) != 3 {
+		t.Fatalf("plan has %d commands, want 3: %q", len(plan.Commands), plan.Commands)
+	}
+	lines := strings.Split(plan.String(), "\n")
+	if want := `tmux send-keys -t %2 'echo it'\''s' C-m`; lines[2] != want {
+		t.Errorf("plan line = %s, want %s", lines[2], want)
+	}
+}
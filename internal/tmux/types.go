@@ -2,13 +2,20 @@ package tmux
 
 // Server represents a tmux server instance and provides methods to interact with it.
 type Server struct {
+	// plan records the commands of a dry run instead of running them
+	plan *Plan
 }
 
 // Session represents a tmux session with its name, attachment status, and working directory.
 type Session struct {
-	Id       string   `json:"id"`
-	Name     string   `json:"name"`
-	Attached bool     `json:"attached"`
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Attached bool   `json:"attached"`
+	// LastAttached is the unix time a client last attached to the session,
+	// 0 if none ever did
+	LastAttached int64 `json:"last_attached"`
+	// Activity is the unix time of the last output in the session
+	Activity int64    `json:"activity"`
 	Path     string   `json:"path"`
 	Windows  []Window `json:"windows"`
 }
@@ -16,10 +23,14 @@ type Session struct {
 // Window represents a tmux window within a session.
 type Window struct {
 	Id            string `json:"id"`
+	Index         int    `json:"index"`
 	Active        bool   `json:"active"`
 	ActiveClients int    `json:"active_clients"`
-	Name          string `json:"name"`
-	Panes         []Pane `json:"panes"`
+	// Layout is the layout string describing the size and position of
+	// every pane, as accepted by select-layout
+	Layout string `json:"layout"`
+	Name   string `json:"name"`
+	Panes  []Pane `json:"panes"`
 }
 
 // Pane represents a tmux pane within a window.
@@ -27,6 +38,13 @@ type Pane struct {
 	Id     string `json:"id"`
 	Index  int    `json:"index"`
 	Active bool   `json:"active"`
+	// Pid is the process ID of the program the pane was started with,
+	// usually a shell
+	Pid int `json:"pid"`
+	// Command is the name of the process running in the foreground
+	Command string `json:"command"`
+	// Path is the current working directory of the pane
+	Path string `json:"path"`
 }
 
 // Direction represents the split direction for panes.
@@ -39,6 +57,19 @@ const (
 	Vertical
 )
 
+// SplitOptions configure the pane created by splitting another.
+type SplitOptions struct {
+	// Direction places the new pane to the right (Horizontal) or below
+	// (Vertical) the split pane
+	Direction      Direction
+	StartDirectory string
+	// Size of the new pane in cells, or as a percentage like "30%".
+	// Empty means half of the split pane.
+	Size string
+	// Command is run in the new pane instead of the default shell
+	Command string
+}
+
 // TmuxContext represents the current execution context relative to tmux.
 type TmuxContext int64
 
@@ -0,0 +1,99 @@
+package tmuxp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// customLayoutPattern matches the checksum prefix of a custom layout string
+var customLayoutPattern = regexp.MustCompile(`^[0-9a-f]{4},`)
+
+// cellPattern matches the size and position of a layout cell: WxH,X,Y
+var cellPattern = regexp.MustCompile(`^\d+x\d+,\d+,\d+`)
+
+// leafPattern matches the pane ID of a leaf cell
+var leafPattern = regexp.MustCompile(`^,\d+`)
+
+// Custom reports whether the layout is a custom layout string, as printed by
+// tmux for #{window_layout}, instead of the name of a preset layout.
+func (t LayoutType) Custom() bool {
+	return customLayoutPattern.MatchString(string(t))
+}
+
+// Panes validates a custom layout string and returns the number of panes it
+// arranges.
+func (t LayoutType) Panes() (int, error) {
+	s := string(t)
+	if !t.Custom() {
+		return 0, errors.New("not a custom layout")
+	}
+	want, _ := strconv.ParseUint(s[:4], 16, 16)
+	if got := checksum(s[5:]); uint64(got) != want {
+		return 0, fmt.Errorf("checksum mismatch: %s, expected %04x", s[:4], got)
+	}
+
+	panes, rest, err := parseCell(s[5:])
+	if err != nil {
+		return 0, err
+	}
+	if rest != "" {
+		return 0, fmt.Errorf("unexpected %q", rest)
+	}
+	return panes, nil
+}
+
+// checksum computes the checksum tmux prefixes custom layouts with.
+func checksum(layout string) uint16 {
+	var csum uint16
+	for i := 0; i < len(layout); i++ {
+		csum = (csum >> 1) + ((csum & 1) << 15)
+		csum += uint16(layout[i])
+	}
+	return csum
+}
+
+// parseCell parses a cell with its children and returns the number of panes
+// in it and the rest of the string.
+func parseCell(s string) (int, string, error) {
+	cell := cellPattern.FindString(s)
+	if cell == "" {
+		return 0, "", fmt.Errorf("expected cell at %q", s)
+	}
+	s = s[len(cell):]
+
+	if len(s) == 0 || (s[0] != '{' && s[0] != '[') {
+		// a pane, its ID is optional and not to be confused with the
+		// size of the next cell
+		if id := leafPattern.FindString(s); id != "" && !strings.HasPrefix(s[len(id):], "x") {
+			s = s[len(id):]
+		}
+		return 1, s, nil
+	}
+
+	closing := byte('}')
+	if s[0] == '[' {
+		closing = ']'
+	}
+	s = s[1:]
+
+	panes := 0
+	for {
+		n, rest, err := parseCell(s)
+		if err != nil {
+			return 0, "", err
+		}
+		panes += n
+		s = rest
+		if len(s) > 0 && s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if len(s) > 0 && s[0] == closing {
+			return panes, s[1:], nil
+		}
+		return 0, "", fmt.Errorf("expected %q at %q", closing, s)
+	}
+}
@@ -0,0 +1,35 @@
+package tmuxp
+
+import "testing"
+
+func TestCustomLayoutPanes(t *testing.T) {
+	tests := []struct {
+		layout LayoutType
+		panes  int
+		ok     bool
+	}{
+		// printed by tmux for #{window_layout}
+		{layout: "b261,80x24,0,0,4", panes: 1, ok: true},
+		{layout: "21be,200x50,0,0{100x50,0,0,0,99x50,101,0[99x25,101,0,1,99x24,101,26,2]}", panes: 3, ok: true},
+		// panes without IDs, as accepted by select-layout
+		{layout: "347e,80x24,0,0{40x24,0,0,39x24,41,0}", panes: 2, ok: true},
+		{layout: "0000,80x24,0,0,4", ok: false},
+		{layout: "21be,200x50,0,0{100x50,0,0,0,99x50,101,0[99x25,101,0,1,99x24,101,26,2]", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.layout), func(t *testing.T) {
+			if !tt.layout.Custom() {
+				t.Fatalf("Expected %q to be a custom layout", tt.layout)
+			}
+			panes, err := tt.layout.Panes()
+			if (err == nil) != tt.ok || panes != tt.panes {
+				t.Errorf("Panes() = %d, %v, want %d, ok %v", panes, err, tt.panes, tt.ok)
+			}
+		})
+	}
+
+	if MainVertical.Custom() {
+		t.Errorf("Expected %q not to be a custom layout", MainVertical)
+	}
+}
@@ -0,0 +1,109 @@
+package tmuxp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateData holds the variables a layout that sets template can use, like
+// {{ .Name }}, so one layout fits many projects.
+type TemplateData struct {
+	// Name is the name of the project directory
+	Name string
+	// Label is the label of the entry in the finder
+	Label string
+	// Path is the project directory
+	Path string
+	// Branch is the git branch checked out in Path, empty outside of a
+	// repository
+	Branch string
+	// Session is the name of the session the layout is applied to, the one
+	// session_name gives if set. Within session_name itself it is the name
+	// derived from the label.
+	Session string
+	// Vars are the vars of the entry in search.entries
+	Vars map[string]string
+}
+
+// isTemplate reports whether a layout document sets template: true.
+func isTemplate(doc *yaml.Node) bool {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "template" {
+			var on bool
+			return root.Content[i+1].Decode(&on) == nil && on
+		}
+	}
+	return false
+}
+
+// renderValues executes every string value below node as template with vars,
+// in place. Values are substituted after the yaml was parsed, so whatever
+// they contain cannot change its structure. Keys are left as they are.
+// missingKey is the template option for vars the entry does not have. It
+// returns the node that failed along with its error.
+func renderValues(node *yaml.Node, vars TemplateData, missingKey string) (*yaml.Node, error) {
+	var values []*yaml.Node
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		values = node.Content
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			values = append(values, node.Content[i])
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" || !strings.Contains(node.Value, "{{") {
+			return nil, nil
+		}
+		tmpl, err := template.New("layout").Option("missingkey=" + missingKey).Parse(node.Value)
+		if err != nil {
+			return node, err
+		}
+		var value strings.Builder
+		if err := tmpl.Execute(&value, vars); err != nil {
+			return node, err
+		}
+		node.Value = value.String()
+	}
+	for _, value := range values {
+		if failed, err := renderValues(value, vars, missingKey); err != nil {
+			return failed, err
+		}
+	}
+	return nil, nil
+}
+
+// templateMessage returns the message of a template error without the
+// template name and the position within the value.
+func templateMessage(err error) string {
+	if match := templateErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+		return match[3]
+	}
+	return err.Error()
+}
+
+// ReadLayoutTemplate reads a layout file like ReadLayoutFromFile. If the
+// layout sets template, its string values are executed as templates with
+// vars first, using a var the entry does not have is an error. Other layouts
+// are read as they are, {{ in their commands is kept.
+func ReadLayoutTemplate(filePath string, vars TemplateData) (*Layout, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || !isTemplate(&doc) {
+		return parseLayout(data)
+	}
+	if node, err := renderValues(&doc, vars, "error"); err != nil {
+		return nil, fmt.Errorf("line %d: template: %s", node.Line, templateMessage(err))
+	}
+	return decodeLayout(&doc)
+}
@@ -1,8 +1,13 @@
 package tmuxp
 
 import (
+	"bytes"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -29,21 +34,190 @@ const (
 	Tiled          LayoutType = "tiled"
 )
 
+// SplitDirection places a pane next to the pane it is split from.
+type SplitDirection string
+
+const (
+	// SplitHorizontal places the pane to the right, the default
+	SplitHorizontal SplitDirection = "horizontal"
+	// SplitVertical places the pane below
+	SplitVertical SplitDirection = "vertical"
+)
+
+// LaunchMode is how the commands of a pane are run.
+type LaunchMode string
+
+const (
+	// LaunchKeys types the commands into the shell of the pane, the default
+	LaunchKeys LaunchMode = "keys"
+	// LaunchProcess starts the pane with its commands as its process instead
+	// of a shell, the pane closes once they exit
+	LaunchProcess LaunchMode = "process"
+)
+
+// sizePattern matches a pane size in cells or percent
+var sizePattern = regexp.MustCompile(`^\d+%?$`)
+
 type Layout struct {
-	Windows []Window `yaml:"windows"`
+	// SessionName names the session instead of the entry label
+	SessionName string `yaml:"session_name,omitempty"`
+	// BeforeScript runs in the session directory before windows are created
+	BeforeScript string `yaml:"before_script,omitempty"`
+	// ShellCommandBefore is sent to every pane before its own commands
+	ShellCommandBefore Commands `yaml:"shell_command_before,omitempty"`
+	// Environment is set in the session for all its panes
+	Environment map[string]string `yaml:"environment,omitempty"`
+	// Options are tmux options of the session
+	Options map[string]string `yaml:"options,omitempty"`
+	// GlobalOptions are tmux options set for the whole server
+	GlobalOptions map[string]string `yaml:"global_options,omitempty"`
+	// SuppressHistory keeps commands out of the shell history, by
+	// prefixing them with a space, unless a window or pane says otherwise
+	SuppressHistory *bool `yaml:"suppress_history,omitempty"`
+	// Launch is how pane commands are run, unless a pane says otherwise
+	Launch LaunchMode `yaml:"launch,omitempty"`
+	// Template executes the string values of the layout as Go templates,
+	// see TemplateData
+	Template bool     `yaml:"template,omitempty"`
+	Windows  []Window `yaml:"windows"`
 }
 
 type Window struct {
-	Name           string     `yaml:"window_name"`
-	Layout         LayoutType `yaml:"layout"`
-	StartDirectory string     `yaml:"start_directory"`
-	Panes          []Pane     `yaml:"panes"`
+	Name string `yaml:"window_name,omitempty"`
+	// Index is the window number, the next free one if not set
+	Index *int `yaml:"window_index,omitempty"`
+	// Focus selects the window once the session is set up
+	Focus              bool              `yaml:"focus,omitempty"`
+	Layout             LayoutType        `yaml:"layout,omitempty"`
+	StartDirectory     string            `yaml:"start_directory,omitempty"`
+	ShellCommandBefore Commands          `yaml:"shell_command_before,omitempty"`
+	Options            map[string]string `yaml:"options,omitempty"`
+	SuppressHistory    *bool             `yaml:"suppress_history,omitempty"`
+	Panes              Panes             `yaml:"panes"`
 }
 
 type Pane struct {
-	ShellCommand   []string `yaml:"shell_command"`
-	Focus          bool     `yaml:"focus"`
-	StartDirectory string   `yaml:"start_directory"`
+	ShellCommand    Commands `yaml:"shell_command,omitempty"`
+	Focus           bool     `yaml:"focus,omitempty"`
+	StartDirectory  string   `yaml:"start_directory,omitempty"`
+	SuppressHistory *bool    `yaml:"suppress_history,omitempty"`
+	// Split is the direction the pane is split off in
+	Split SplitDirection `yaml:"split,omitempty"`
+	// Size of the pane in cells, or as a percentage like "30%"
+	Size string `yaml:"size,omitempty"`
+	// Target is the position in the window of the earlier pane to split,
+	// the first pane by default
+	Target int `yaml:"target,omitempty"`
+	// Launch is how the commands of the pane are run
+	Launch LaunchMode `yaml:"launch,omitempty"`
+}
+
+// Commands is a list of shell commands. Like tmuxp, it accepts a single
+// command as a plain string, and list items as strings or as objects with a
+// cmd key.
+type Commands []string
+
+// UnmarshalYAML decodes a single command or a list of commands.
+func (c *Commands) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" || node.Value == "" {
+			*c = nil
+			return nil
+		}
+		*c = Commands{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		commands := make(Commands, 0, len(node.Content))
+		for _, item := range node.Content {
+			switch item.Kind {
+			case yaml.ScalarNode:
+				commands = append(commands, item.Value)
+			case yaml.MappingNode:
+				var command struct {
+					Cmd string `yaml:"cmd"`
+				}
+				if err := item.Decode(&command); err != nil {
+					return err
+				}
+				commands = append(commands, command.Cmd)
+			default:
+				return fmt.Errorf("line %d: expected command as string or object", item.Line)
+			}
+		}
+		*c = commands
+		return nil
+	}
+	return fmt.Errorf("line %d: expected command as string or list", node.Line)
+}
+
+// UnmarshalYAML accepts the shorthands of tmuxp for a pane: a plain string is
+// its shell command, an empty string, "blank" or "pane" an empty pane.
+func (p *Pane) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		switch {
+		case node.ShortTag() == "!!null", node.Value == "", node.Value == "blank", node.Value == "pane":
+			*p = Pane{}
+		default:
+			*p = Pane{ShellCommand: Commands{node.Value}}
+		}
+		return nil
+	}
+
+	// decode the full form without recursing into this method
+	type pane Pane
+	return node.Decode((*pane)(p))
+}
+
+// Panes is the list of panes of a window.
+type Panes []Pane
+
+// UnmarshalYAML decodes every item into a pane, yaml.v3 would drop null items
+// that tmuxp takes as empty panes.
+func (p *Panes) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: expected list of panes", node.Line)
+	}
+	panes := make(Panes, len(node.Content))
+	for i, item := range node.Content {
+		if err := panes[i].UnmarshalYAML(item); err != nil {
+			return err
+		}
+	}
+	*p = panes
+	return nil
+}
+
+// Env returns the environment as KEY=VALUE pairs, sorted by key.
+func (l Layout) Env() []string {
+	keys := slices.Sorted(maps.Keys(l.Environment))
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+l.Environment[key])
+	}
+	return env
+}
+
+// Suppress reports whether the commands of pane i of window w are kept out of
+// the shell history. The pane overrides the window, which overrides the layout.
+func (l Layout) Suppress(w int, i int) bool {
+	for _, suppress := range []*bool{l.Windows[w].Panes[i].SuppressHistory, l.Windows[w].SuppressHistory, l.SuppressHistory} {
+		if suppress != nil {
+			return *suppress
+		}
+	}
+	return false
+}
+
+// PaneLaunch returns how the commands of pane i of window w are run. The pane
+// overrides the layout.
+func (l Layout) PaneLaunch(w int, i int) LaunchMode {
+	for _, launch := range []LaunchMode{l.Windows[w].Panes[i].Launch, l.Launch} {
+		if launch != "" {
+			return launch
+		}
+	}
+	return LaunchKeys
 }
 
 func Simple(name string, path string) Layout {
@@ -64,26 +238,78 @@ func Simple(name string, path string) Layout {
 	}
 }
 
+// validLaunch reports whether launch is a known launch mode, or not set.
+func validLaunch(launch LaunchMode) bool {
+	return launch == "" || launch == LaunchKeys || launch == LaunchProcess
+}
+
 func ReadLayoutFromFile(filePath string) (*Layout, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return parseLayout(data)
+}
 
+// parseLayout decodes and checks a layout, expanding its paths.
+func parseLayout(data []byte) (*Layout, error) {
 	var layout Layout
-	err = yaml.Unmarshal(data, &layout)
+	err := yaml.Unmarshal(data, &layout)
 	if err != nil {
 		return nil, err
 	}
+	return checkLayout(layout)
+}
+
+// decodeLayout decodes and checks a layout from its yaml document.
+func decodeLayout(doc *yaml.Node) (*Layout, error) {
+	var layout Layout
+	if err := doc.Decode(&layout); err != nil {
+		return nil, err
+	}
+	return checkLayout(layout)
+}
 
+// checkLayout rejects layouts that cannot be applied and expands the paths of
+// the rest.
+func checkLayout(layout Layout) (*Layout, error) {
 	if len(layout.Windows) == 0 {
 		return nil, fmt.Errorf("layout must have at least one window")
 	}
 
+	layout.BeforeScript = ExpandPath(layout.BeforeScript)
+
+	if !validLaunch(layout.Launch) {
+		return nil, fmt.Errorf("launch must be %s or %s", LaunchKeys, LaunchProcess)
+	}
+
 	for i, window := range layout.Windows {
 		if len(window.Panes) == 0 {
 			return nil, fmt.Errorf("window %d must have at least one pane", i)
 		}
+		if window.Layout.Custom() {
+			panes, err := window.Layout.Panes()
+			if err != nil {
+				return nil, fmt.Errorf("window %d: invalid layout: %w", i, err)
+			}
+			if panes != len(window.Panes) {
+				return nil, fmt.Errorf("window %d: layout arranges %d panes, window has %d", i, panes, len(window.Panes))
+			}
+		}
+		for j, pane := range window.Panes {
+			if pane.Split != "" && pane.Split != SplitHorizontal && pane.Split != SplitVertical {
+				return nil, fmt.Errorf("window %d pane %d: split must be %s or %s", i, j, SplitHorizontal, SplitVertical)
+			}
+			if pane.Size != "" && !sizePattern.MatchString(pane.Size) {
+				return nil, fmt.Errorf("window %d pane %d: size must be cells or a percentage", i, j)
+			}
+			if pane.Target < 0 || (pane.Target > 0 && pane.Target >= j) {
+				return nil, fmt.Errorf("window %d pane %d: target must be an earlier pane", i, j)
+			}
+			if !validLaunch(pane.Launch) {
+				return nil, fmt.Errorf("window %d pane %d: launch must be %s or %s", i, j, LaunchKeys, LaunchProcess)
+			}
+		}
 		// Expand ~ and environment variables in window start directory
 		layout.Windows[i].StartDirectory = ExpandPath(window.StartDirectory)
 
@@ -97,3 +323,43 @@ func ReadLayoutFromFile(filePath string) (*Layout, error) {
 
 	return &layout, nil
 }
+
+// Marshal encodes the layout as YAML, as read by ReadLayoutFromFile.
+func Marshal(layout Layout) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(layout); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteLayoutToFile writes the layout as YAML to filePath. Unless overwrite is
+// set, an existing file is left untouched and an error is returned.
+func WriteLayoutToFile(layout Layout, filePath string, overwrite bool) error {
+	data, err := Marshal(layout)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(filePath, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
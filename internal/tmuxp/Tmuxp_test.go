@@ -1,6 +1,10 @@
 package tmuxp
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -174,3 +178,289 @@ func TestYAMLMarshaling(t *testing.T) {
 		}
 	}
 }
+
+func TestReadFullSchemaLayout(t *testing.T) {
+	layout, err := ReadLayoutFromFile("testdata/full_schema_layout.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read layout from file: %v", err)
+	}
+
+	if layout.SessionName != "api" {
+		t.Errorf("Expected session name 'api', got '%s'", layout.SessionName)
+	}
+	if layout.BeforeScript != "./bootstrap.sh" {
+		t.Errorf("Expected before script './bootstrap.sh', got '%s'", layout.BeforeScript)
+	}
+	if len(layout.ShellCommandBefore) != 1 || layout.ShellCommandBefore[0] != "source .env" {
+		t.Errorf("Expected shell command before [source .env], got %v", layout.ShellCommandBefore)
+	}
+	if env := layout.Env(); len(env) != 2 || env[0] != "EDITOR=nvim" || env[1] != "PORT=8080" {
+		t.Errorf("Expected environment [EDITOR=nvim PORT=8080], got %v", env)
+	}
+	if layout.Options["base-index"] != "1" {
+		t.Errorf("Expected option base-index 1, got %v", layout.Options)
+	}
+	if layout.GlobalOptions["mouse"] != "on" {
+		t.Errorf("Expected global option mouse on, got %v", layout.GlobalOptions)
+	}
+
+	edit := layout.Windows[0]
+	if edit.Index == nil || *edit.Index != 3 {
+		t.Errorf("Expected window index 3, got %v", edit.Index)
+	}
+	if edit.Focus {
+		t.Error("Expected first window not to have focus")
+	}
+	if edit.Options["automatic-rename"] != "off" {
+		t.Errorf("Expected window option automatic-rename off, got %v", edit.Options)
+	}
+	if !layout.Windows[1].Focus {
+		t.Error("Expected second window to have focus")
+	}
+	if layout.Windows[1].Index != nil {
+		t.Errorf("Expected no window index, got %d", *layout.Windows[1].Index)
+	}
+
+	// the pane overrides the window, which overrides the layout
+	if !layout.Suppress(0, 0) {
+		t.Error("Expected history suppressed in edit pane 1")
+	}
+	if layout.Suppress(0, 1) {
+		t.Error("Expected history not suppressed in edit pane 2")
+	}
+	if layout.Suppress(1, 0) {
+		t.Error("Expected history not suppressed in server pane")
+	}
+}
+
+func TestReadShorthandLayout(t *testing.T) {
+	layout, err := ReadLayoutFromFile("testdata/shorthand_layout.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read layout from file: %v", err)
+	}
+
+	if len(layout.ShellCommandBefore) != 1 || layout.ShellCommandBefore[0] != "source .env" {
+		t.Errorf("Expected shell command before [source .env], got %v", layout.ShellCommandBefore)
+	}
+
+	window := layout.Windows[0]
+	if len(window.ShellCommandBefore) != 1 || window.ShellCommandBefore[0] != "nvm use" {
+		t.Errorf("Expected window shell command before [nvm use], got %v", window.ShellCommandBefore)
+	}
+
+	tests := []struct {
+		name string
+		want Pane
+	}{
+		{name: "bare string", want: Pane{ShellCommand: Commands{"vim"}}},
+		{name: "null", want: Pane{}},
+		{name: "tilde", want: Pane{}},
+		{name: "blank", want: Pane{}},
+		{name: "pane", want: Pane{}},
+		{name: "empty string", want: Pane{}},
+		{name: "shell_command as string", want: Pane{ShellCommand: Commands{"git status"}}},
+		{name: "shell_command with cmd objects", want: Pane{ShellCommand: Commands{"make", "run"}}},
+		{name: "empty shell_command", want: Pane{}},
+		{name: "full form", want: Pane{Focus: true}},
+	}
+
+	if len(window.Panes) != len(tests) {
+		t.Fatalf("Expected %d panes, got %d", len(tests), len(window.Panes))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := window.Panes[i]
+			if !slices.Equal(got.ShellCommand, tt.want.ShellCommand) || got.Focus != tt.want.Focus {
+				t.Errorf("Expected pane %d to be %+v, got %+v", i, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUnmarshalInvalidShellCommand(t *testing.T) {
+	var pane Pane
+	err := yaml.Unmarshal([]byte("shell_command:\n  - [nested]\n"), &pane)
+	if err == nil {
+		t.Error("Expected error for nested shell command list, got nil")
+	}
+}
+
+func TestReadSplitLayout(t *testing.T) {
+	layout, err := ReadLayoutFromFile("testdata/split_layout.yaml")
+	if err != nil {
+		t.Fatalf("Failed to read layout from file: %v", err)
+	}
+
+	panes := layout.Windows[0].Panes
+	if panes[1].Split != SplitVertical || panes[1].Size != "30%" || panes[1].Target != 0 {
+		t.Errorf("Expected vertical split of 30%% from pane 0, got %+v", panes[1])
+	}
+	if panes[2].Split != SplitHorizontal || panes[2].Size != "40" || panes[2].Target != 1 {
+		t.Errorf("Expected horizontal split of 40 cells from pane 1, got %+v", panes[2])
+	}
+	if !layout.Windows[1].Layout.Custom() {
+		t.Errorf("Expected custom layout, got %s", layout.Windows[1].Layout)
+	}
+}
+
+func TestPaneLaunch(t *testing.T) {
+	layout := Layout{
+		Launch: LaunchProcess,
+		Windows: []Window{
+			{Panes: []Pane{{}, {Launch: LaunchKeys}}},
+		},
+	}
+
+	if got := layout.PaneLaunch(0, 0); got != LaunchProcess {
+		t.Errorf("Expected pane 0 to inherit %s, got %s", LaunchProcess, got)
+	}
+	if got := layout.PaneLaunch(0, 1); got != LaunchKeys {
+		t.Errorf("Expected pane 1 to override with %s, got %s", LaunchKeys, got)
+	}
+	layout.Launch = ""
+	if got := layout.PaneLaunch(0, 0); got != LaunchKeys {
+		t.Errorf("Expected default %s, got %s", LaunchKeys, got)
+	}
+}
+
+func TestReadInvalidSplitLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		panes string
+	}{
+		{name: "unknown direction", panes: "[blank, {split: diagonal}]"},
+		{name: "invalid size", panes: "[blank, {size: half}]"},
+		{name: "target not earlier", panes: "[blank, {target: 1}]"},
+		{name: "unknown launch mode", panes: "[{launch: exec}]"},
+		{name: "custom layout with other pane count", panes: "[blank]\n    layout: \"347e,80x24,0,0{40x24,0,0,39x24,41,0}\""},
+		{name: "custom layout with wrong checksum", panes: "[blank]\n    layout: \"0000,80x24,0,0\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "layout.yaml")
+			data := "windows:\n  - panes: " + tt.panes + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadLayoutFromFile(path); err == nil {
+				t.Errorf("Expected error for %s, got nil", tt.name)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	problems, err := ValidateFile("testdata/invalid_layout.yaml")
+	if err != nil {
+		t.Fatalf("ValidateFile() error: %v", err)
+	}
+
+	want := []string{
+		`2:1: unknown session key "shell_commands_before"`,
+		`5:13: unknown layout "main-vert", expected one of even-horizontal, even-vertical, main-horizontal, main-vertical, tiled or a custom layout`,
+		`6:22: start directory /nonexistent/sessionizer does not exist`,
+		`11:16: window 0 has more than one focused pane`,
+		`12:9: unknown pane key "size_"`,
+	}
+	got := make([]string, len(problems))
+	for i, problem := range problems {
+		got[i] = problem.String()
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ValidateFile() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, file := range []string{"testdata/full_schema_layout.yaml", "testdata/split_layout.yaml", "testdata/template_layout.yaml"} {
+		problems, err := ValidateFile(file)
+		if err != nil || len(problems) > 0 {
+			t.Errorf("ValidateFile(%s) = %v, %v, want no problems", file, problems, err)
+		}
+	}
+}
+
+func TestValidateYamlErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "syntax", data: "windows:\n  - panes: [blank\n", want: "1: did not find expected ',' or ']'"},
+		{name: "type", data: "windows:\n  - focus: maybe\n    panes: [blank]\n", want: "2: cannot unmarshal !!str `maybe` into bool"},
+		{name: "no windows", data: "session_name: empty\n", want: "layout must have at least one window"},
+		{name: "template", data: "template: true\nwindows:\n  - panes: ['{{ .Path }']\n", want: `3:13: template: unexpected "}" in operand`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Validate([]byte(tt.data))
+			if len(problems) != 1 || problems[0].String() != tt.want {
+				t.Errorf("Validate() = %v, want %q", problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLayoutTemplate(t *testing.T) {
+	data := TemplateData{
+		Name:    "app",
+		Label:   "org/app",
+		Path:    "/src/org/app",
+		Branch:  "main",
+		Session: "org/app",
+		Vars:    map[string]string{"packages": "./..."},
+	}
+	layout, err := ReadLayoutTemplate("testdata/template_layout.yaml", data)
+	if err != nil {
+		t.Fatalf("ReadLayoutTemplate() error: %v", err)
+	}
+
+	if layout.SessionName != "org/app" {
+		t.Errorf("Expected session name 'org/app', got '%s'", layout.SessionName)
+	}
+	window := layout.Windows[0]
+	if window.Name != "org/app (app)" {
+		t.Errorf("Expected window name 'org/app (app)', got '%s'", window.Name)
+	}
+	if window.StartDirectory != "/src/org/app" {
+		t.Errorf("Expected start directory '/src/org/app', got '%s'", window.StartDirectory)
+	}
+	if got := window.Panes[0].ShellCommand; len(got) != 1 || got[0] != "go test ./..." {
+		t.Errorf("Expected pane command [go test ./...], got %v", got)
+	}
+	if got := window.Panes[1].ShellCommand; len(got) != 1 || got[0] != "git log main" {
+		t.Errorf("Expected pane command [git log main], got %v", got)
+	}
+
+	// values are substituted into the parsed layout, quotes, colons and
+	// newlines cannot change its structure
+	data.Label = "it's: \"odd\"\nfocus: true"
+	layout, err = ReadLayoutTemplate("testdata/template_layout.yaml", data)
+	if err != nil {
+		t.Fatalf("ReadLayoutTemplate() error: %v", err)
+	}
+	if want := data.Label + " (app)"; layout.Windows[0].Name != want || layout.Windows[0].Focus {
+		t.Errorf("Expected window name %q without focus, got %q", want, layout.Windows[0].Name)
+	}
+
+	data.Vars = nil
+	_, err = ReadLayoutTemplate("testdata/template_layout.yaml", data)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 8: template: ") {
+		t.Errorf("Expected error for missing var at line 8, got %v", err)
+	}
+}
+
+func TestReadLayoutWithoutTemplate(t *testing.T) {
+	// layouts that don't set template keep {{ in their commands
+	path := filepath.Join(t.TempDir(), "layout.yaml")
+	data := "windows:\n  - panes:\n      - docker ps --format '{{.Names}}'\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layout, err := ReadLayoutTemplate(path, TemplateData{})
+	if err != nil {
+		t.Fatalf("ReadLayoutTemplate() error: %v", err)
+	}
+	if got := layout.Windows[0].Panes[0].ShellCommand; len(got) != 1 || got[0] != "docker ps --format '{{.Names}}'" {
+		t.Errorf("Expected pane command kept as written, got %v", got)
+	}
+}
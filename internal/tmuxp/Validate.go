@@ -0,0 +1,238 @@
+package tmuxp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// presetLayouts are the layout names tmux knows
+var presetLayouts = []LayoutType{EvenHorizontal, EvenVertical, MainHorizontal, MainVertical, Tiled}
+
+// yamlLinePattern matches the line yaml reports an error at
+var yamlLinePattern = regexp.MustCompile(`line (\d+): `)
+
+// templateErrorPattern matches the position and message of a template error
+var templateErrorPattern = regexp.MustCompile(`^template: [^:]*:(\d+):(?:(\d+):)? (.*)$`)
+
+var (
+	layoutKeys = yamlKeys(Layout{})
+	windowKeys = yamlKeys(Window{})
+	paneKeys   = yamlKeys(Pane{})
+)
+
+// Problem is an issue found in a layout file. Line and Column are 1-based,
+// 0 if the problem is not about a single place in the file.
+type Problem struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.Line == 0:
+		return p.Message
+	case p.Column == 0:
+		return fmt.Sprintf("%d: %s", p.Line, p.Message)
+	default:
+		return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+	}
+}
+
+// yamlKeys returns the keys of a layout struct, as named by its yaml tags.
+func yamlKeys(v any) map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// validator collects the problems of a layout while walking its yaml nodes.
+type validator struct {
+	problems []Problem
+	// skipDirectories leaves out start directories, as they may be built
+	// from template variables
+	skipDirectories bool
+}
+
+func (v *validator) report(node *yaml.Node, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// fields checks the keys of a mapping and returns its values by key.
+func (v *validator) fields(node *yaml.Node, known map[string]bool, what string) map[string]*yaml.Node {
+	values := map[string]*yaml.Node{}
+	if node.Kind != yaml.MappingNode {
+		return values
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !known[key.Value] {
+			v.report(key, "unknown %s key %q", what, key.Value)
+			continue
+		}
+		values[key.Value] = node.Content[i+1]
+	}
+	return values
+}
+
+// startDirectory checks that an absolute start directory exists. Relative
+// ones depend on the session and are not checked.
+func (v *validator) startDirectory(node *yaml.Node) {
+	if v.skipDirectories || node == nil || node.Value == "" {
+		return
+	}
+	dir := ExpandPath(node.Value)
+	if !filepath.IsAbs(dir) {
+		return
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		v.report(node, "start directory %s does not exist", dir)
+	}
+}
+
+// layoutValue checks that a window layout is a preset or a valid custom
+// layout string.
+func (v *validator) layoutValue(node *yaml.Node) {
+	layout := LayoutType(node.Value)
+	if slices.Contains(presetLayouts, layout) {
+		return
+	}
+	if !layout.Custom() {
+		v.report(node, "unknown layout %q, expected one of %s or a custom layout", node.Value, strings.Join(presetNames(), ", "))
+		return
+	}
+	if _, err := layout.Panes(); err != nil {
+		v.report(node, "invalid custom layout: %v", err)
+	}
+}
+
+// presetNames lists the preset layouts for messages.
+func presetNames() []string {
+	names := make([]string, len(presetLayouts))
+	for i, layout := range presetLayouts {
+		names[i] = string(layout)
+	}
+	return names
+}
+
+// window checks window w and its panes.
+func (v *validator) window(node *yaml.Node, w int) {
+	fields := v.fields(node, windowKeys, "window")
+	if layout, ok := fields["layout"]; ok {
+		v.layoutValue(layout)
+	}
+	v.startDirectory(fields["start_directory"])
+
+	panes, ok := fields["panes"]
+	if !ok || panes.Kind != yaml.SequenceNode {
+		return
+	}
+	focused := 0
+	for _, pane := range panes.Content {
+		paneFields := v.fields(pane, paneKeys, "pane")
+		v.startDirectory(paneFields["start_directory"])
+		if focus, ok := paneFields["focus"]; ok {
+			if on, _ := strconv.ParseBool(focus.Value); on {
+				focused++
+				if focused > 1 {
+					v.report(focus, "window %d has more than one focused pane", w)
+				}
+			}
+		}
+	}
+}
+
+// Validate checks a layout file for problems, with their position in the
+// file where possible: unknown keys, unknown window layouts, missing start
+// directories and more than one focused pane per window. Once there are none,
+// the checks ReadLayoutFromFile does are reported as well.
+//
+// Templates are executed with empty variables, start directories of
+// templates are not checked.
+func Validate(data []byte) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return yamlProblems(err)
+	}
+	if len(doc.Content) == 0 {
+		return []Problem{{Message: "layout is empty"}}
+	}
+
+	templated := isTemplate(&doc)
+	if templated {
+		if node, err := renderValues(&doc, TemplateData{}, "zero"); err != nil {
+			return []Problem{{Line: node.Line, Column: node.Column, Message: "template: " + templateMessage(err)}}
+		}
+	}
+
+	v := &validator{skipDirectories: templated}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		v.report(root, "layout must be a mapping")
+		return v.problems
+	}
+	fields := v.fields(root, layoutKeys, "session")
+	if windows, ok := fields["windows"]; ok && windows.Kind == yaml.SequenceNode {
+		for w, window := range windows.Content {
+			v.window(window, w)
+		}
+	}
+	if len(v.problems) > 0 {
+		slices.SortStableFunc(v.problems, func(a, b Problem) int {
+			if a.Line != b.Line {
+				return a.Line - b.Line
+			}
+			return a.Column - b.Column
+		})
+		return v.problems
+	}
+
+	if _, err := decodeLayout(&doc); err != nil {
+		return yamlProblems(err)
+	}
+	return nil
+}
+
+// ValidateFile reads and validates a layout file.
+func ValidateFile(filePath string) ([]Problem, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(data), nil
+}
+
+// yamlProblems converts an error, placing it at the line yaml reported.
+func yamlProblems(err error) []Problem {
+	messages := []string{strings.TrimPrefix(err.Error(), "yaml: ")}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	problems := make([]Problem, 0, len(messages))
+	for _, message := range messages {
+		problem := Problem{Message: message}
+		if match := yamlLinePattern.FindStringSubmatchIndex(message); match != nil {
+			problem.Line, _ = strconv.Atoi(message[match[2]:match[3]])
+			problem.Message = message[:match[0]] + message[match[1]:]
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
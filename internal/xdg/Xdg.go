@@ -0,0 +1,36 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "sessionizer"
+
+// dir resolves an XDG base directory for this application: the value of env
+// when set to an absolute path, otherwise fallback below the home directory.
+func dir(env string, fallback ...string) (string, error) {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), appName)...), nil
+}
+
+// CacheDir returns $XDG_CACHE_HOME/sessionizer, or ~/.cache/sessionizer.
+func CacheDir() (string, error) {
+	return dir("XDG_CACHE_HOME", ".cache")
+}
+
+// DataDir returns $XDG_DATA_HOME/sessionizer, or ~/.local/share/sessionizer.
+func DataDir() (string, error) {
+	return dir("XDG_DATA_HOME", ".local", "share")
+}
+
+// StateDir returns $XDG_STATE_HOME/sessionizer, or ~/.local/state/sessionizer.
+func StateDir() (string, error) {
+	return dir("XDG_STATE_HOME", ".local", "state")
+}
@@ -5,6 +5,26 @@ type SearchEntry struct {
 	Path   string
 	Name   string
 	Layout string
+	// Vars are variables for the layout template of the entry
+	Vars map[string]string
+}
+
+// SearchDir represents a directory to scan for projects, with optional
+// settings that apply to this directory only
+type SearchDir struct {
+	Path string
+	// MinDepth skips projects less than this many levels below Path
+	MinDepth int
+	// MaxDepth stops descending more than this many levels below Path,
+	// 0 means unlimited
+	MaxDepth       int
+	FollowSymlinks bool
+	// Ignore holds patterns added to the global ones for this directory
+	Ignore []string
+	// RooterPatterns replace the global rooter patterns if set
+	RooterPatterns []string
+	// NestedRooterPatterns replace the global nested rooter patterns if set
+	NestedRooterPatterns []string
 }
 
 // Config holds the application configuration
@@ -13,8 +33,19 @@ type Config struct {
 	DefaultPath string
 	// DefaultLayoutPath is a direct path to a layout file for the default entry
 	DefaultLayoutPath string
-	SearchDirs        []string
+	SearchDirs        []SearchDir
 	SearchEntries     []SearchEntry
-	Ignore            []string
-	RooterPatterns    []string
+	// Ignore holds gitignore-style patterns for directories to skip
+	Ignore []string
+	// IgnoreFiles enables reading a .sessionizerignore in each search directory
+	IgnoreFiles    bool
+	RooterPatterns []string
+	// NestedRooterPatterns, if set, find sub-projects inside projects,
+	// e.g. the packages of a monorepo
+	NestedRooterPatterns []string
+	// Worktrees offers linked git worktrees as entries of their own
+	Worktrees bool
+	// Workers bounds the number of directories scanned concurrently,
+	// 0 means one per CPU
+	Workers int
 }
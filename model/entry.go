@@ -2,10 +2,15 @@ package model
 
 // Entry represents a searchable project or directory
 type Entry struct {
-	Label string
-	Path  string
+	Label string `json:"label"`
+	Path  string `json:"path"`
 	// Layout names a layout resolved from configDir/layouts/<name>.yml
-	Layout string
+	Layout string `json:"layout,omitempty"`
 	// LayoutPath is a direct path to a layout file (env and ~ expanded)
-	LayoutPath string
+	LayoutPath string `json:"layout_path,omitempty"`
+	// Session is the exact name of a running tmux session the entry stands
+	// for, set for sessions that don't belong to any configured project
+	Session string `json:"session,omitempty"`
+	// Vars are variables for the layout template, from search.entries
+	Vars map[string]string `json:"vars,omitempty"`
 }